@@ -2,13 +2,19 @@ package compiler
 
 import (
 	"errors"
+	"fmt"
 	"go/ast"
 	"go/build"
 	"go/parser"
 	"go/token"
 	"io"
+	"math"
+	"math/big"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/tcard/gojure/lang"
 	"github.com/tcard/gojure/persistent"
@@ -18,6 +24,12 @@ import (
 // Compile Gojure source coe into a Go AST.
 func Compile(r io.Reader) (*ast.File, error) {
 	gr := reader.From(r)
+	gr.Resolve = func(sym lang.Symbol) lang.Symbol {
+		if _, ok := Symbols.m[sym.Name]; ok {
+			return lang.Symbol{NS: reader.CoreNS, Name: sym.Name}
+		}
+		return lang.Symbol{NS: gr.NS, Name: sym.Name}
+	}
 
 	env := &SymExprsTable{
 		imports: make(map[string][]string),
@@ -70,7 +82,7 @@ func Compile(r io.Reader) (*ast.File, error) {
 			break
 		}
 		if expr != nil {
-			main.Body.List = append(main.Body.List, &ast.DeclStmt{&ast.GenDecl{
+			main.Body.List = append(main.Body.List, &ast.DeclStmt{Decl: &ast.GenDecl{
 				Tok: token.VAR,
 				Specs: []ast.Spec{&ast.ValueSpec{
 					Names:  []*ast.Ident{identExpr("_")},
@@ -98,6 +110,7 @@ func Compile(r io.Reader) (*ast.File, error) {
 		var _ *persistent.List
 		var _ lang.Symbol
 		var _ reflect.Type
+		var _ *regexp.Regexp
 
 		type SymTable struct {
 			parent *SymTable
@@ -132,6 +145,13 @@ func Compile(r io.Reader) (*ast.File, error) {
 										Value: &ast.InterfaceType{Methods: &ast.FieldList{}}},
 									Elts: []ast.Expr{}}}}}}}}}},
 		main)
+	for i, pattern := range env.regexps {
+		file.Decls = append(file.Decls, &ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{
+				Names:  []*ast.Ident{regexpVarIdent(i)},
+				Values: []ast.Expr{mustCompileExpr(pattern)}}}})
+	}
 
 	return file, nil
 }
@@ -141,12 +161,48 @@ func CompileString(s string) (*ast.File, error) {
 	return Compile(strings.NewReader(s))
 }
 
+// An Error is an error found while compiling a form, with the line and column
+// where the innermost list containing the problem was read.
+type Error struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %v", e.Line, e.Column, e.Err)
+}
+
+// Gives err as an *Error at the position in meta, as attached by the reader, if it
+// isn't one already.
+func errorAt(meta *persistent.HashMap, err error) error {
+	if _, ok := err.(*Error); ok || meta == nil {
+		return err
+	}
+	line, _ := meta.Get(lang.Keyword("line"))
+	column, _ := meta.Get(lang.Keyword("column"))
+	l, ok1 := line.(int)
+	c, ok2 := column.(int)
+	if !ok1 || !ok2 {
+		return err
+	}
+	return &Error{Line: l, Column: c, Err: err}
+}
+
 // Compiles a single Gojure form into a Go expression, returning side effects on the symbol
 // table (definitions, etc.) in a new value.
 func CompileForm(form interface{}, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
 	switch vform := form.(type) {
 	case int:
 		return &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(vform)}, env, nil
+	case float64:
+		if math.IsInf(vform, 0) || math.IsNaN(vform) {
+			// Infinities and NaN have no Go literal.
+			return parseNumberExpr(vform), env, nil
+		}
+		return &ast.BasicLit{Kind: token.FLOAT, Value: lang.NumberLiteral(vform)}, env, nil
+	case *big.Int, *big.Rat, *lang.BigDecimal:
+		return parseNumberExpr(vform), env, nil
 	case bool:
 		if vform {
 			return identExpr("true"), env, nil
@@ -159,54 +215,129 @@ func CompileForm(form interface{}, env *SymExprsTable) (ast.Expr, *SymExprsTable
 			Args: []ast.Expr{identExpr("nil")},
 		}, env, nil
 	case string:
-		return &ast.BasicLit{Kind: token.STRING, Value: `"` + vform + `"`}, env, nil
+		return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(vform)}, env, nil
+	case lang.Char:
+		return &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X:   identExpr("lang"),
+				Sel: identExpr("Char"),
+			},
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.CHAR, Value: strconv.QuoteRune(rune(vform))}},
+		}, env, nil
+	case lang.Keyword:
+		return &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X:   identExpr("lang"),
+				Sel: identExpr("Keyword"),
+			},
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(string(vform))}},
+		}, env, nil
+	case *regexp.Regexp:
+		if env == nil {
+			return mustCompileExpr(vform.String()), env, nil
+		}
+		return env.regexpVar(vform.String()), env, nil
+	case time.Time:
+		return &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X:   identExpr("lang"),
+				Sel: identExpr("MustParseInst"),
+			},
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(vform.Format(time.RFC3339Nano))}},
+		}, env, nil
+	case lang.UUID:
+		return &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X:   identExpr("lang"),
+				Sel: identExpr("MustParseUUID"),
+			},
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(vform.String())}},
+		}, env, nil
 	case lang.Symbol:
 		return compileSymbol(vform, env)
 	case *persistent.List:
-		opform := vform.First()
-		sym, isSym := opform.(lang.Symbol)
-
-		if isSym {
-			switch sym.Name {
-			case "def":
-				return compileDef(vform.Rest(), env)
-			case "fn*":
-				return compileFn(vform.Rest(), env)
-			case "if":
-				return compileIf(vform.Rest(), env)
-			case "quote":
-				if vform.Rest() == nil {
-					return CompileForm(nil, env)
-				}
-				q, err := quote(vform.Rest().First())
-				return q, env, err
-			case "import":
-				if vform.Rest() == nil {
-					return CompileForm(nil, env)
-				}
-				alias := ""
-				if vform.Rest().Rest() != nil {
-					alias = vform.Rest().Rest().First().(lang.Symbol).Name
-				}
-				err := env.Import(vform.Rest().First().(string), alias)
-				if err != nil {
-					return nil, env, err
-				}
-				return CompileForm(nil, env)
-			}
+		if vform == nil {
+			q, err := quote(vform)
+			return q, env, err
 		}
-
-		return compileCall(vform, env)
+		expr, env, err := compileList(vform, env)
+		if err != nil {
+			err = errorAt(vform.Meta(), err)
+		}
+		return expr, env, err
 	case *persistent.Vector:
 		return compileVector(vform, env, false)
+	case *persistent.HashMap:
+		return compileMap(vform, env, false)
+	case *persistent.HashSet:
+		return compileSet(vform, env, false)
+	case *persistent.Queue:
+		return compileQueue(vform, env, false)
 	}
-	return nil, env, nil
+	return nil, env, fmt.Errorf("can't compile a value of type %T.", form)
+}
+
+// Compiles a non-empty list, as a special form or as a call.
+func compileList(vform *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
+	opform := vform.First()
+	sym, isSym := opform.(lang.Symbol)
+
+	if isSym {
+		switch sym.Name {
+		case "def":
+			return compileDef(vform.Rest(), env)
+		case "fn*":
+			return compileFn(vform.Rest(), env)
+		case "if":
+			return compileIf(vform.Rest(), env)
+		case "let*":
+			return compileLet(vform.Rest(), env)
+		case "do":
+			return compileDo(vform.Rest(), env)
+		case "loop*":
+			return compileLoop(vform.Rest(), env)
+		case "recur":
+			return nil, env, errors.New("can only recur from tail position.")
+		case "var":
+			// #'x reads as (var x), but there are no vars: a def just
+			// binds a symbol to a value.
+			return nil, env, errors.New("var is not supported, refer to the symbol itself.")
+		case "comment":
+			return CompileForm(nil, env)
+		case "quote":
+			if vform.Rest() == nil {
+				return CompileForm(nil, env)
+			}
+			q, err := quote(vform.Rest().First())
+			return q, env, err
+		case "import":
+			if vform.Rest() == nil {
+				return CompileForm(nil, env)
+			}
+			alias := ""
+			if vform.Rest().Rest() != nil {
+				alias = vform.Rest().Rest().First().(lang.Symbol).Name
+			}
+			err := env.Import(vform.Rest().First().(string), alias)
+			if err != nil {
+				return nil, env, err
+			}
+			return CompileForm(nil, env)
+		}
+	}
+
+	return compileCall(vform, env)
 }
 
 func compileSymbol(sym lang.Symbol, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
+	if sym.NS == "" {
+		if e, ok := env.local(sym.Name); ok {
+			return e, env, nil
+		}
+	}
 	if e, ok := env.Get(sym.Name, sym.NS); !ok {
 		return nil, env, errors.New("Undefined symbol: " + sym.String())
-	} else if sym.NS != "" {
+	} else if sym.NS != "" && env.imported(sym.NS) {
 		return e, env, nil
 	}
 	return &ast.CallExpr{
@@ -219,12 +350,17 @@ func compileSymbol(sym lang.Symbol, env *SymExprsTable) (ast.Expr, *SymExprsTabl
 
 func compileDef(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
 	ident := form.First().(lang.Symbol).Name
-	env.m[ident] = nil // &ast.BasicLit{Kind: token.STRING, Value: "`placeholder`"}
+	// Definitions go to the symbols table, not to the locals in scope.
+	defEnv := env
+	for defEnv.locals {
+		defEnv = defEnv.parent
+	}
+	defEnv.m[ident] = nil // &ast.BasicLit{Kind: token.STRING, Value: "`placeholder`"}
 	def, env, err := CompileForm(form.Rest().First(), env)
 	if err != nil {
 		return nil, env, err
 	}
-	env.m[ident] = def
+	defEnv.m[ident] = def
 	return &ast.CallExpr{
 		Args: []ast.Expr{},
 		Fun: &ast.FuncLit{
@@ -243,63 +379,415 @@ func compileDef(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsT
 				&ast.ReturnStmt{Results: []ast.Expr{identExpr("nil")}}}}}}, env, nil
 }
 
+// Compiles (fn* [params] body...), or (fn* ([params] body...) ...) for a function
+// with several arities, into a Go func that runs the body for the arity that
+// matches the number of arguments it's called with, or panics with a
+// *lang.ArityException if none does. An arity's parameters may end in & rest,
+// which makes it variadic: rest is then bound to a list of the rest of the
+// arguments, or nil if there are none. A name may come before the arities, as in
+// (fn* fact [n] ...); it is then bound to the function itself in its body.
 func compileFn(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
-	args := form.First().(*persistent.Vector)
-	bodyf := form.Rest().First()
-	fnEnv := &SymExprsTable{parent: env, m: map[string]ast.Expr{}}
-	for i := 0; i < args.Count(); i++ {
-		fnEnv.m[args.Nth(i).(lang.Symbol).Name] = &ast.IndexExpr{
+	name := "fn"
+	fnEnv := env
+	var self *ast.Ident
+	if form == nil {
+		return nil, env, errors.New("fn* requires a parameter vector or a list of arities.")
+	}
+	if sym, ok := form.First().(lang.Symbol); ok {
+		if sym.NS != "" {
+			return nil, env, errors.New("fn* name must be an unqualified symbol, got: " + sym.String() + ".")
+		}
+		name = sym.Name
+		self = env.newLocal(sym.Name)
+		fnEnv = &SymExprsTable{parent: env, m: map[string]ast.Expr{sym.Name: self}, locals: true}
+		form = form.Rest()
+	}
+	if form == nil {
+		return nil, env, errors.New("fn* requires a parameter vector or a list of arities.")
+	}
+	arities := []*persistent.List{form}
+	if _, ok := form.First().(*persistent.Vector); !ok {
+		arities = arities[:0]
+		for l := form; l != nil; l = l.Rest() {
+			arity, ok := l.First().(*persistent.List)
+			if !ok || arity == nil {
+				return nil, env, errors.New("fn* requires a parameter vector or a list of arities.")
+			}
+			if _, ok := arity.First().(*persistent.Vector); !ok {
+				return nil, env, errors.New("fn* arity must start with a parameter vector.")
+			}
+			arities = append(arities, arity)
+		}
+	}
+
+	fixed := map[int]bool{}
+	var variadic *ast.CaseClause
+	variadicRequired := 0
+	cases := []ast.Stmt{}
+	for _, arity := range arities {
+		stmts, required, isVariadic, err := compileArity(arity, fnEnv)
+		if err != nil {
+			return nil, env, errorAt(arity.Meta(), err)
+		}
+		op := token.EQL
+		if isVariadic {
+			if variadic != nil {
+				return nil, env, errors.New("can't have more than 1 variadic overload.")
+			}
+			op = token.GEQ
+			variadicRequired = required
+		} else if fixed[required] {
+			return nil, env, errors.New("can't have 2 overloads with same arity.")
+		}
+		clause := &ast.CaseClause{
+			List: []ast.Expr{&ast.BinaryExpr{
+				X:  &ast.CallExpr{Fun: identExpr("len"), Args: []ast.Expr{identExpr("xs")}},
+				Op: op,
+				Y:  &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(required)},
+			}},
+			Body: stmts,
+		}
+		if isVariadic {
+			variadic = clause
+		} else {
+			fixed[required] = true
+			cases = append(cases, clause)
+		}
+	}
+	if variadic != nil {
+		for n := range fixed {
+			if n > variadicRequired {
+				return nil, env, errors.New("can't have fixed arity function with more params than variadic function.")
+			}
+		}
+		cases = append(cases, variadic)
+	}
+
+	arityErr, _ := parser.ParseExpr(`&lang.ArityException{Actual: len(xs), Name: ` + strconv.Quote(name) + `}`)
+	fn := &ast.CallExpr{
+		Fun: ifaceAST,
+		Args: []ast.Expr{&ast.FuncLit{
+			Type: fnAST,
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.SwitchStmt{Body: &ast.BlockStmt{List: cases}},
+				&ast.ExprStmt{X: &ast.CallExpr{Fun: identExpr("panic"), Args: []ast.Expr{arityErr}}},
+			}}}}}
+	if self == nil {
+		return fn, env, nil
+	}
+	// The function must be declared before it's assigned so that it can refer
+	// to itself.
+	return callClosure([]ast.Stmt{
+		&ast.DeclStmt{Decl: &ast.GenDecl{
+			Tok:   token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{Names: []*ast.Ident{self}, Type: ifaceAST}}}},
+		&ast.AssignStmt{Lhs: []ast.Expr{self}, Tok: token.ASSIGN, Rhs: []ast.Expr{fn}},
+		&ast.ReturnStmt{Results: []ast.Expr{self}},
+	}), env, nil
+}
+
+// Compiles an arity of a fn*, ([params] body...), into the statements that bind
+// its parameters to xs and run its body, where a recur goes back to the start of
+// the body with the parameters rebound. It gives how many arguments the arity
+// requires, and whether it takes any more after them.
+func compileArity(form *persistent.List, env *SymExprsTable) ([]ast.Stmt, int, bool, error) {
+	params := form.First().(*persistent.Vector)
+	names := []string{}
+	inits := []ast.Expr{}
+	variadic := false
+	for i := 0; i < params.Count(); i++ {
+		sym, ok := params.Nth(i).(lang.Symbol)
+		if !ok || sym.NS != "" {
+			return nil, 0, false, fmt.Errorf("fn* params must be unqualified symbols, got: %v.", params.Nth(i))
+		}
+		if sym.Name == "&" {
+			if i != params.Count()-2 {
+				return nil, 0, false, errors.New("fn* must have exactly one parameter after &.")
+			}
+			rest, ok := params.Nth(i + 1).(lang.Symbol)
+			if !ok || rest.NS != "" || rest.Name == "&" {
+				return nil, 0, false, fmt.Errorf("fn* params must be unqualified symbols, got: %v.", params.Nth(i+1))
+			}
+			// The rest of the arguments, as a list, or nil if there are none.
+			restInit, _ := parser.ParseExpr(`func() interface{} {
+				if len(xs) > ` + strconv.Itoa(i) + ` {
+					return persistent.NewList(xs[` + strconv.Itoa(i) + `:]...)
+				}
+				return nil
+			}()`)
+			names = append(names, rest.Name)
+			inits = append(inits, restInit)
+			variadic = true
+			break
+		}
+		names = append(names, sym.Name)
+		inits = append(inits, &ast.IndexExpr{
 			X:     identExpr("xs"),
 			Index: &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(i)},
-		}
+		})
+	}
+	required := len(names)
+	if variadic {
+		required--
 	}
-	newSyms := &ast.CompositeLit{
-		Type: &ast.MapType{
-			Key:   identExpr("string"),
-			Value: &ast.InterfaceType{Methods: &ast.FieldList{}}},
-		Elts: []ast.Expr{}}
-	newTable := &ast.UnaryExpr{
-		Op: token.AND,
-		X: &ast.CompositeLit{
-			Type: identExpr("SymTable"),
-			Elts: []ast.Expr{
-				&ast.KeyValueExpr{
-					Key:   identExpr("parent"),
-					Value: identExpr("symbols")},
-				&ast.KeyValueExpr{
-					Key:   identExpr("m"),
-					Value: newSyms,
-				}}}}
 
-	body, fnEnv, err := CompileForm(bodyf, fnEnv)
+	target := &recurTarget{}
+	inners := []*ast.Ident{}
+	fnEnv := &SymExprsTable{parent: env, m: map[string]ast.Expr{}, locals: true}
+	for _, name := range names {
+		target.locals = append(target.locals, env.newLocal(name))
+		inner := env.newLocal(name)
+		inners = append(inners, inner)
+		fnEnv.m[name] = inner
+	}
+	body, err := compileBody(form.Rest(), fnEnv, target)
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, false, err
 	}
-	ret := &ast.FuncLit{
-		Type: fnAST,
-		Body: &ast.BlockStmt{
-			List: []ast.Stmt{
-				&ast.AssignStmt{
-					Lhs: []ast.Expr{identExpr("symbols")},
-					Tok: token.DEFINE,
-					Rhs: []ast.Expr{newTable}}}}}
-	for k, v := range fnEnv.m {
-		ret.Body.List = append(ret.Body.List,
+	if target.used {
+		return append(declareLocals(target.locals, inits), recurLoop(target.locals, inners, body)), required, variadic, nil
+	}
+	return append(declareLocals(inners, inits), body...), required, variadic, nil
+}
+
+// Compiles (do expr ...) into a closure, called right away, that evaluates each
+// expression in order and gives the value of the last one, or nil if there are
+// none.
+func compileDo(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
+	if form == nil {
+		return CompileForm(nil, env)
+	}
+	if form.Rest() == nil {
+		return CompileForm(form.First(), env)
+	}
+	body, err := compileBody(form, env, nil)
+	if err != nil {
+		return nil, env, err
+	}
+	return callClosure(body), env, nil
+}
+
+// Compiles the expressions in a body, as in fn*, let* or do, into statements
+// that evaluate each of them in order and return the value of the last one, or
+// nil if there are none. The last one is in tail position, so it may recur to
+// target, if not nil.
+func compileBody(forms *persistent.List, env *SymExprsTable, target *recurTarget) ([]ast.Stmt, error) {
+	stmts := []ast.Stmt{}
+	if forms == nil {
+		return append(stmts, &ast.ReturnStmt{Results: []ast.Expr{identExpr("nil")}}), nil
+	}
+	for ; forms.Rest() != nil; forms = forms.Rest() {
+		expr, _, err := CompileForm(forms.First(), env)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, &ast.AssignStmt{
+			Lhs: []ast.Expr{identExpr("_")},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{expr}})
+	}
+	tail, err := compileTail(forms.First(), env, target)
+	if err != nil {
+		return nil, err
+	}
+	return append(stmts, tail...), nil
+}
+
+// A recurTarget is the loop* or fn* that a recur in its tail position goes back
+// to the start of, rebinding its locals.
+type recurTarget struct {
+	// The Go variables a recur assigns its arguments to.
+	locals []*ast.Ident
+	// Whether there's a recur to the target.
+	used bool
+}
+
+// Compiles a form in tail position into statements that return its value or,
+// for a recur to target, go back to the start of the target's loop. if, do and
+// let* forms are compiled into statements, so that the forms in their tail
+// position are too.
+func compileTail(form interface{}, env *SymExprsTable, target *recurTarget) ([]ast.Stmt, error) {
+	if l, ok := form.(*persistent.List); ok && l != nil {
+		var stmts []ast.Stmt
+		var err error
+		sym, _ := l.First().(lang.Symbol)
+		switch sym.Name {
+		case "if":
+			stmts, err = compileIfStmts(l.Rest(), env, target)
+		case "do":
+			stmts, err = compileBody(l.Rest(), env, target)
+		case "let*":
+			stmts, err = compileLetStmts(l.Rest(), env, target)
+		case "recur":
+			stmts, err = compileRecur(l.Rest(), env, target)
+		default:
+			expr, _, err := CompileForm(form, env)
+			return []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{expr}}}, err
+		}
+		if err != nil {
+			return nil, errorAt(l.Meta(), err)
+		}
+		return stmts, nil
+	}
+	expr, _, err := CompileForm(form, env)
+	return []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{expr}}}, err
+}
+
+// Compiles (recur expr ...) into statements that assign the values of the
+// expressions to the locals of target and go back to the start of its loop.
+func compileRecur(form *persistent.List, env *SymExprsTable, target *recurTarget) ([]ast.Stmt, error) {
+	if target == nil {
+		return nil, errors.New("can only recur from tail position.")
+	}
+	args := []ast.Expr{}
+	for ; form != nil; form = form.Rest() {
+		arg, _, err := CompileForm(form.First(), env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) != len(target.locals) {
+		return nil, fmt.Errorf("mismatched argument count to recur, expected: %d args, got: %d.", len(target.locals), len(args))
+	}
+	target.used = true
+	stmts := []ast.Stmt{}
+	if len(args) > 0 {
+		lhs := []ast.Expr{}
+		for _, local := range target.locals {
+			lhs = append(lhs, local)
+		}
+		stmts = append(stmts, &ast.AssignStmt{Lhs: lhs, Tok: token.ASSIGN, Rhs: args})
+	}
+	return append(stmts, &ast.BranchStmt{Tok: token.CONTINUE}), nil
+}
+
+// Makes the for loop for a body that a recur goes back to the start of. Each
+// iteration copies the values of locals into its own inners, which the body
+// uses, so that closures made in an iteration keep its values.
+func recurLoop(locals, inners []*ast.Ident, body []ast.Stmt) ast.Stmt {
+	values := []ast.Expr{}
+	for _, local := range locals {
+		values = append(values, local)
+	}
+	return &ast.ForStmt{Body: &ast.BlockStmt{List: append(declareLocals(inners, values), body...)}}
+}
+
+// Makes the statements that declare each of locals with the corresponding value.
+func declareLocals(locals []*ast.Ident, values []ast.Expr) []ast.Stmt {
+	stmts := []ast.Stmt{}
+	for i, local := range locals {
+		stmts = append(stmts,
+			&ast.DeclStmt{Decl: &ast.GenDecl{
+				Tok: token.VAR,
+				Specs: []ast.Spec{&ast.ValueSpec{
+					Names:  []*ast.Ident{local},
+					Type:   ifaceAST,
+					Values: []ast.Expr{values[i]}}}}},
+			// Go doesn't allow unused locals.
 			&ast.AssignStmt{
-				Lhs: []ast.Expr{
-					&ast.IndexExpr{
-						X: &ast.SelectorExpr{
-							X:   identExpr("symbols"),
-							Sel: identExpr("m")},
-						Index: &ast.BasicLit{Kind: token.STRING, Value: "`" + k + "`"},
-					}},
+				Lhs: []ast.Expr{identExpr("_")},
 				Tok: token.ASSIGN,
-				Rhs: []ast.Expr{v}})
+				Rhs: []ast.Expr{local}})
 	}
-	ret.Body.List = append(ret.Body.List, &ast.ReturnStmt{Results: []ast.Expr{body}})
+	return stmts
+}
+
+// Makes a call to a closure, with no parameters, that runs body.
+func callClosure(body []ast.Stmt) ast.Expr {
 	return &ast.CallExpr{
-		Fun:  ifaceAST,
-		Args: []ast.Expr{ret}}, env, nil
+		Fun: &ast.FuncLit{
+			Type: &ast.FuncType{
+				Params:  &ast.FieldList{},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: ifaceAST}}}},
+			Body: &ast.BlockStmt{List: body}}}
+}
+
+// Compiles (let* [name init ...] body...) into a closure, called right away, in which
+// each name is a Go local variable, initialized in order so that each init sees
+// the names before it.
+func compileLet(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
+	stmts, err := compileLetStmts(form, env, nil)
+	if err != nil {
+		return nil, env, err
+	}
+	return callClosure(stmts), env, nil
+}
+
+// Compiles the bindings and body of a let* into statements, with the body in
+// tail position for target.
+func compileLetStmts(form *persistent.List, env *SymExprsTable, target *recurTarget) ([]ast.Stmt, error) {
+	letEnv, _, stmts, err := compileBindings("let*", form, env)
+	if err != nil {
+		return nil, err
+	}
+	body, err := compileBody(form.Rest(), letEnv, target)
+	if err != nil {
+		return nil, err
+	}
+	return append(stmts, body...), nil
+}
+
+// Compiles the binding vector of a let* or loop*, the first item in form, into
+// the statements that declare a Go local variable for each name. It gives the
+// table with the names bound, and the locals for each binding, in order.
+func compileBindings(op string, form *persistent.List, env *SymExprsTable) (*SymExprsTable, []*ast.Ident, []ast.Stmt, error) {
+	if form == nil {
+		return nil, nil, nil, errors.New(op + " requires a vector for its bindings.")
+	}
+	bindings, ok := form.First().(*persistent.Vector)
+	if !ok {
+		return nil, nil, nil, errors.New(op + " requires a vector for its bindings.")
+	}
+	if bindings.Count()%2 != 0 {
+		return nil, nil, nil, errors.New(op + " requires an even number of forms in binding vector.")
+	}
+	bindEnv := &SymExprsTable{parent: env, m: map[string]ast.Expr{}, locals: true}
+	locals := []*ast.Ident{}
+	stmts := []ast.Stmt{}
+	for i := 0; i < bindings.Count(); i += 2 {
+		sym, ok := bindings.Nth(i).(lang.Symbol)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("bad binding form, expected symbol, got: %v.", bindings.Nth(i))
+		}
+		if sym.NS != "" {
+			return nil, nil, nil, errors.New("can't let qualified name: " + sym.String() + ".")
+		}
+		init, _, err := CompileForm(bindings.Nth(i+1), bindEnv)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		local := bindEnv.newLocal(sym.Name)
+		bindEnv.m[sym.Name] = local
+		locals = append(locals, local)
+		stmts = append(stmts, declareLocals([]*ast.Ident{local}, []ast.Expr{init})...)
+	}
+	return bindEnv, locals, stmts, nil
+}
+
+// Compiles (loop* [name init ...] body...) into a closure, called right away,
+// with the bindings as in let*, that runs the body in a for loop which a recur
+// in its tail position goes back to the start of.
+func compileLoop(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
+	bindEnv, locals, stmts, err := compileBindings("loop*", form, env)
+	if err != nil {
+		return nil, env, err
+	}
+	bindings := form.First().(*persistent.Vector)
+	target := &recurTarget{locals: locals}
+	inners := []*ast.Ident{}
+	loopEnv := &SymExprsTable{parent: env, m: map[string]ast.Expr{}, locals: true}
+	for i := 0; i < bindings.Count(); i += 2 {
+		name := bindings.Nth(i).(lang.Symbol).Name
+		inner := bindEnv.newLocal(name)
+		inners = append(inners, inner)
+		loopEnv.m[name] = inner
+	}
+	body, err := compileBody(form.Rest(), loopEnv, target)
+	if err != nil {
+		return nil, env, err
+	}
+	return callClosure(append(stmts, recurLoop(locals, inners, body))), env, nil
 }
 
 func compileCall(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
@@ -327,85 +815,107 @@ func compileCall(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprs
 }
 
 func compileIf(form *persistent.List, env *SymExprsTable) (ast.Expr, *SymExprsTable, error) {
-	cond, env, err := CompileForm(form.First(), env)
+	stmts, err := compileIfStmts(form, env, nil)
 	if err != nil {
 		return nil, env, err
 	}
-	yes, env, err := CompileForm(form.Rest().First(), env)
+	return callClosure(stmts), env, nil
+}
+
+// Compiles (if test then else?) into an if statement whose branches return the
+// value of then or else, or nil if there is no else. Both are in tail position
+// for target.
+func compileIfStmts(form *persistent.List, env *SymExprsTable, target *recurTarget) ([]ast.Stmt, error) {
+	n := 0
+	for l := form; l != nil; l = l.Rest() {
+		n++
+	}
+	if n < 2 {
+		return nil, errors.New("too few arguments to if.")
+	} else if n > 3 {
+		return nil, errors.New("too many arguments to if.")
+	}
+	cond, _, err := CompileForm(form.First(), env)
 	if err != nil {
-		return nil, env, err
+		return nil, err
 	}
-	no, env, err := CompileForm(form.Rest().Rest().First(), env)
+	yes, err := compileTail(form.Rest().First(), env, target)
 	if err != nil {
-		return nil, env, err
+		return nil, err
 	}
-	return &ast.CallExpr{
-		Args: []ast.Expr{},
-		Fun: &ast.FuncLit{
-			Type: &ast.FuncType{
-				Params: &ast.FieldList{List: []*ast.Field{}},
-				Results: &ast.FieldList{List: []*ast.Field{
-					{Type: ifaceAST},
-				}}},
-			Body: &ast.BlockStmt{List: []ast.Stmt{
-				&ast.DeclStmt{&ast.GenDecl{
-					Tok: token.VAR,
-					Specs: []ast.Spec{&ast.ValueSpec{
-						Type:  ifaceAST,
-						Names: []*ast.Ident{identExpr("ifRet")},
-					}}}},
-				&ast.AssignStmt{
-					Lhs: []ast.Expr{identExpr("ifCond")},
-					Tok: token.DEFINE,
-					Rhs: []ast.Expr{&ast.CallExpr{
-						Fun:  ifaceAST,
-						Args: []ast.Expr{cond},
-					}},
-				},
-				&ast.IfStmt{
-					Cond: &ast.CallExpr{
-						Fun: &ast.SelectorExpr{
-							X:   identExpr("lang"),
-							Sel: identExpr("IsFalse"),
-						},
-						Args: []ast.Expr{identExpr("ifCond")},
-					},
-					Body: &ast.BlockStmt{List: []ast.Stmt{
-						&ast.AssignStmt{
-							Lhs: []ast.Expr{identExpr("ifRet")},
-							Tok: token.ASSIGN,
-							Rhs: []ast.Expr{no},
-						}}},
-					Else: &ast.BlockStmt{List: []ast.Stmt{
-						&ast.AssignStmt{
-							Lhs: []ast.Expr{identExpr("ifRet")},
-							Tok: token.ASSIGN,
-							Rhs: []ast.Expr{yes},
-						}}},
-				},
-				&ast.ReturnStmt{Results: []ast.Expr{identExpr("ifRet")}},
-			}}},
-	}, env, nil
+	var noForm interface{}
+	if n == 3 {
+		noForm = form.Rest().Rest().First()
+	}
+	no, err := compileTail(noForm, env, target)
+	if err != nil {
+		return nil, err
+	}
+	return []ast.Stmt{&ast.IfStmt{
+		Cond: &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X:   identExpr("lang"),
+				Sel: identExpr("IsFalse"),
+			},
+			Args: []ast.Expr{cond},
+		},
+		Body: &ast.BlockStmt{List: no},
+		Else: &ast.BlockStmt{List: yes},
+	}}, nil
 }
 
 func compileVector(v *persistent.Vector, env *SymExprsTable, quoting bool) (ast.Expr, *SymExprsTable, error) {
+	items := []interface{}{}
+	for i := 0; i < v.Count(); i++ {
+		items = append(items, v.Nth(i))
+	}
+	return compileCollection("persistent", "NewVector", items, env, quoting)
+}
+
+func compileMap(m *persistent.HashMap, env *SymExprsTable, quoting bool) (ast.Expr, *SymExprsTable, error) {
+	items := []interface{}{}
+	for it := m.Iterator(); it.Next(); {
+		items = append(items, it.Key(), it.Val())
+	}
+	return compileCollection("lang", "NewHashMap", items, env, quoting)
+}
+
+func compileSet(s *persistent.HashSet, env *SymExprsTable, quoting bool) (ast.Expr, *SymExprsTable, error) {
+	items := []interface{}{}
+	for it := s.Iterator(); it.Next(); {
+		items = append(items, it.Item())
+	}
+	return compileCollection("lang", "NewHashSet", items, env, quoting)
+}
+
+func compileQueue(q *persistent.Queue, env *SymExprsTable, quoting bool) (ast.Expr, *SymExprsTable, error) {
+	items := []interface{}{}
+	for it := q.Iterator(); it.Next(); {
+		items = append(items, it.Item())
+	}
+	return compileCollection("persistent", "NewQueue", items, env, quoting)
+}
+
+// Compiles a call to the constructor ctor from package pkg with items as
+// arguments, quoting them if asked to.
+func compileCollection(pkg, ctor string, items []interface{}, env *SymExprsTable, quoting bool) (ast.Expr, *SymExprsTable, error) {
 	ret := &ast.CallExpr{
 		Fun: &ast.SelectorExpr{
-			X:   identExpr("persistent"),
-			Sel: identExpr("NewVector"),
+			X:   identExpr(pkg),
+			Sel: identExpr(ctor),
 		},
 		Args: []ast.Expr{},
 	}
 	var item ast.Expr
 	var err error
-	for i := 0; i < v.Count(); i++ {
+	for _, x := range items {
 		if quoting {
-			item, err = quote(v.Nth(i))
+			item, err = quote(x)
 			if err != nil {
 				return nil, env, err
 			}
 		} else {
-			item, env, err = CompileForm(v.Nth(i), env)
+			item, env, err = CompileForm(x, env)
 			if err != nil {
 				return nil, env, err
 			}
@@ -415,6 +925,18 @@ func compileVector(v *persistent.Vector, env *SymExprsTable, quoting bool) (ast.
 	return ret, env, err
 }
 
+// Gives an expression that makes the number x by parsing its literal at run time,
+// for numbers that have no Go literal.
+func parseNumberExpr(x interface{}) ast.Expr {
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   identExpr("lang"),
+			Sel: identExpr("MustParseNumber"),
+		},
+		Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(lang.NumberLiteral(x))}},
+	}
+}
+
 func quote(thingy interface{}) (ast.Expr, error) {
 	switch v := thingy.(type) {
 	case lang.Symbol:
@@ -424,8 +946,14 @@ func quote(thingy interface{}) (ast.Expr, error) {
 				Sel: identExpr("Symbol"),
 			},
 			Elts: []ast.Expr{
-				&ast.BasicLit{Kind: token.STRING, Value: `"` + v.NS + `"`},
-				&ast.BasicLit{Kind: token.STRING, Value: `"` + v.Name + `"`},
+				&ast.KeyValueExpr{
+					Key:   identExpr("NS"),
+					Value: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v.NS)},
+				},
+				&ast.KeyValueExpr{
+					Key:   identExpr("Name"),
+					Value: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v.Name)},
+				},
 			},
 		}, nil
 	case *persistent.List:
@@ -448,11 +976,31 @@ func quote(thingy interface{}) (ast.Expr, error) {
 	case *persistent.Vector:
 		e, _, err := compileVector(v, nil, true)
 		return e, err
+	case *persistent.HashMap:
+		e, _, err := compileMap(v, nil, true)
+		return e, err
+	case *persistent.HashSet:
+		e, _, err := compileSet(v, nil, true)
+		return e, err
+	case *persistent.Queue:
+		e, _, err := compileQueue(v, nil, true)
+		return e, err
 	}
 	v, _, err := CompileForm(thingy, nil)
 	return v, err
 }
 
+// Gives a regexp.MustCompile call for pattern.
+func mustCompileExpr(pattern string) ast.Expr {
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   identExpr("regexp"),
+			Sel: identExpr("MustCompile"),
+		},
+		Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(pattern)}},
+	}
+}
+
 var ifaceAST = func() ast.Expr {
 	expr, _ := parser.ParseExpr(`interface{}`)
 	return expr
@@ -475,6 +1023,14 @@ type SymExprsTable struct {
 	parent  *SymExprsTable
 	m       map[string]ast.Expr
 	imports map[string][]string
+	// Patterns of the regexp literals found, each compiled once into a
+	// package-level var.
+	regexps []string
+	// Whether the expressions in m are Go local variables, as bound by let*,
+	// instead of entries in the runtime symbols table.
+	locals bool
+	// How many Go local variables have been made, to give each a unique name.
+	nlocals int
 }
 
 func (st SymExprsTable) Get(s string, ns string) (ast.Expr, bool) {
@@ -494,6 +1050,62 @@ func (st SymExprsTable) Get(s string, ns string) (ast.Expr, bool) {
 	return nil, false
 }
 
+// Tells whether ns is the name of an imported Go package.
+func (st SymExprsTable) imported(ns string) bool {
+	if _, ok := st.imports[ns]; ok {
+		return true
+	}
+	return st.parent != nil && st.parent.imported(ns)
+}
+
+// Gives the package-level var that holds the compiled regexp for pattern,
+// adding it to the root table if it isn't there yet.
+func (st *SymExprsTable) regexpVar(pattern string) ast.Expr {
+	for st.parent != nil {
+		st = st.parent
+	}
+	i := 0
+	for i < len(st.regexps) && st.regexps[i] != pattern {
+		i++
+	}
+	if i == len(st.regexps) {
+		st.regexps = append(st.regexps, pattern)
+	}
+	return regexpVarIdent(i)
+}
+
+// Gives the Go local variable that name is bound to, if it is bound to one in
+// the innermost table that has it.
+func (st *SymExprsTable) local(name string) (ast.Expr, bool) {
+	for ; st != nil; st = st.parent {
+		if e, ok := st.m[name]; ok {
+			return e, st.locals
+		}
+	}
+	return nil, false
+}
+
+// Makes a new identifier, unique in the compiled file, for a Go local variable
+// that will hold the local named name.
+func (st *SymExprsTable) newLocal(name string) *ast.Ident {
+	root := st
+	for root.parent != nil {
+		root = root.parent
+	}
+	root.nlocals++
+	goName := strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return c
+		}
+		return '_'
+	}, name)
+	return identExpr(goName + "__" + strconv.Itoa(root.nlocals))
+}
+
+func regexpVarIdent(i int) *ast.Ident {
+	return identExpr("regexp" + strconv.Itoa(i))
+}
+
 func (st SymExprsTable) Import(pkgName string, alias string) error {
 	pkg, err := build.Import(pkgName, ".", build.AllowBinary)
 	if err != nil {
@@ -518,6 +1130,7 @@ var Symbols = &SymExprsTable{
 	imports: map[string][]string{
 		"fmt":        []string{"fmt"},
 		"reflect":    []string{"reflect"},
+		"regexp":     []string{"regexp"},
 		"persistent": []string{"github.com/tcard/gojure/persistent"},
 		"lang":       []string{"github.com/tcard/gojure/lang"},
 	},
@@ -525,28 +1138,94 @@ var Symbols = &SymExprsTable{
 		"apply": func() ast.Expr {
 			e, _ := parser.ParseExpr(`
 			func(xs ...interface{}) interface{} {
-				if len(xs) != 2 {
+				if len(xs) < 2 {
 					panic("bad number of arguments to apply.")
 				}
-				args := []interface{}{}
-				argsL := xs[1].(*persistent.List)
-				for argsL != nil {
-					args = append(args, argsL.First())
-					argsL = argsL.Rest()
-				}
+				args := append([]interface{}{}, xs[1:len(xs)-1]...)
+				args = append(args, lang.Items(xs[len(xs)-1])...)
 				return xs[0].(func(...interface{}) interface{})(args...)
 			}`)
 			return e
 		}(),
+		"list": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				return persistent.NewList(xs...)
+			}`)
+			return e
+		}(),
+		"vector": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				return persistent.NewVector(xs...)
+			}`)
+			return e
+		}(),
+		"hash-map": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs)%2 != 0 {
+					panic("bad number of arguments to hash-map.")
+				}
+				return lang.NewHashMap(xs...)
+			}`)
+			return e
+		}(),
+		"hash-set": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				return lang.NewHashSet(xs...)
+			}`)
+			return e
+		}(),
+		"seq": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 1 {
+					panic("bad number of arguments to seq.")
+				}
+				items := lang.Items(xs[0])
+				if len(items) == 0 {
+					return nil
+				}
+				return persistent.NewList(items...)
+			}`)
+			return e
+		}(),
+		"concat": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				items := []interface{}{}
+				for _, x := range xs {
+					items = append(items, lang.Items(x)...)
+				}
+				return persistent.NewList(items...)
+			}`)
+			return e
+		}(),
 		"+": func() ast.Expr {
 			e, _ := parser.ParseExpr(`
 			func(xs ...interface{}) interface{} {
 				if len(xs) == 0 {
 					return 0
 				}
-				ret := xs[0].(int)
+				ret := xs[0]
+				for _, x := range xs[1:] {
+					ret = lang.Add(ret, x)
+				}
+				return ret
+			}`)
+			return e
+		}(),
+		"+'": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					return 0
+				}
+				ret := xs[0]
 				for _, x := range xs[1:] {
-					ret += x.(int)
+					ret = lang.AddP(ret, x)
 				}
 				return ret
 			}`)
@@ -555,9 +1234,32 @@ var Symbols = &SymExprsTable{
 		"-": func() ast.Expr {
 			e, _ := parser.ParseExpr(`
 			func(xs ...interface{}) interface{} {
-				ret := xs[0].(int)
+				if len(xs) == 0 {
+					panic("bad number of arguments to -.")
+				}
+				if len(xs) == 1 {
+					return lang.Subtract(0, xs[0])
+				}
+				ret := xs[0]
+				for _, x := range xs[1:] {
+					ret = lang.Subtract(ret, x)
+				}
+				return ret
+			}`)
+			return e
+		}(),
+		"-'": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					panic("bad number of arguments to -'.")
+				}
+				if len(xs) == 1 {
+					return lang.SubtractP(0, xs[0])
+				}
+				ret := xs[0]
 				for _, x := range xs[1:] {
-					ret -= x.(int)
+					ret = lang.SubtractP(ret, x)
 				}
 				return ret
 			}`)
@@ -569,31 +1271,113 @@ var Symbols = &SymExprsTable{
 				if len(xs) == 0 {
 					return 1
 				}
-				ret := xs[0].(int)
+				ret := xs[0]
+				for _, x := range xs[1:] {
+					ret = lang.Multiply(ret, x)
+				}
+				return ret
+			}`)
+			return e
+		}(),
+		"*'": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					return 1
+				}
+				ret := xs[0]
 				for _, x := range xs[1:] {
-					ret *= x.(int)
+					ret = lang.MultiplyP(ret, x)
 				}
 				return ret
-			}	`)
+			}`)
 			return e
 		}(),
 		"/": func() ast.Expr {
 			e, _ := parser.ParseExpr(`
 			func(xs ...interface{}) interface{} {
-				ret := xs[0].(int)
+				if len(xs) == 0 {
+					panic("bad number of arguments to /.")
+				}
+				if len(xs) == 1 {
+					return lang.Divide(1, xs[0])
+				}
+				ret := xs[0]
 				for _, x := range xs[1:] {
-					ret /= x.(int)
+					ret = lang.Divide(ret, x)
 				}
 				return ret
 			}`)
 			return e
 		}(),
+		"<": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					panic("bad number of arguments to <.")
+				}
+				for i := 1; i < len(xs); i++ {
+					if !(lang.Compare(xs[i-1], xs[i]) < 0) {
+						return false
+					}
+				}
+				return true
+			}`)
+			return e
+		}(),
+		">": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					panic("bad number of arguments to >.")
+				}
+				for i := 1; i < len(xs); i++ {
+					if !(lang.Compare(xs[i-1], xs[i]) > 0) {
+						return false
+					}
+				}
+				return true
+			}`)
+			return e
+		}(),
+		"<=": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					panic("bad number of arguments to <=.")
+				}
+				for i := 1; i < len(xs); i++ {
+					if !(lang.Compare(xs[i-1], xs[i]) <= 0) {
+						return false
+					}
+				}
+				return true
+			}`)
+			return e
+		}(),
+		">=": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					panic("bad number of arguments to >=.")
+				}
+				for i := 1; i < len(xs); i++ {
+					if !(lang.Compare(xs[i-1], xs[i]) >= 0) {
+						return false
+					}
+				}
+				return true
+			}`)
+			return e
+		}(),
 		"=": func() ast.Expr {
 			e, _ := parser.ParseExpr(`
 			func(xs ...interface{}) interface{} {
-				ret := xs[0].(int)
-				for _, x := range xs[1:] {
-					if x.(int) != ret {
+				if len(xs) == 0 {
+					panic("bad number of arguments to =.")
+				}
+				for i := 1; i < len(xs); i++ {
+					if !lang.Equiv(xs[i-1], xs[i]) {
 						return false
 					}
 				}
@@ -601,6 +1385,113 @@ var Symbols = &SymExprsTable{
 			}`)
 			return e
 		}(),
+		"not=": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					panic("bad number of arguments to not=.")
+				}
+				for i := 1; i < len(xs); i++ {
+					if !lang.Equiv(xs[i-1], xs[i]) {
+						return true
+					}
+				}
+				return false
+			}`)
+			return e
+		}(),
+		"hash": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 1 {
+					panic("bad number of arguments to hash.")
+				}
+				return int(lang.Hash(xs[0]))
+			}`)
+			return e
+		}(),
+		"re-pattern": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 1 {
+					panic("bad number of arguments to re-pattern.")
+				}
+				return lang.RePattern(xs[0])
+			}`)
+			return e
+		}(),
+		"re-matcher": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 2 {
+					panic("bad number of arguments to re-matcher.")
+				}
+				return lang.NewMatcher(xs[0].(*regexp.Regexp), xs[1].(string))
+			}`)
+			return e
+		}(),
+		"re-find": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				switch len(xs) {
+				case 1:
+					m := xs[0].(*lang.Matcher)
+					if !m.Find() {
+						return nil
+					}
+					return m.Groups()
+				case 2:
+					return lang.ReFind(xs[0].(*regexp.Regexp), xs[1].(string))
+				}
+				panic("bad number of arguments to re-find.")
+			}`)
+			return e
+		}(),
+		"re-groups": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 1 {
+					panic("bad number of arguments to re-groups.")
+				}
+				return lang.ReGroups(xs[0].(*lang.Matcher))
+			}`)
+			return e
+		}(),
+		"re-matches": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 2 {
+					panic("bad number of arguments to re-matches.")
+				}
+				return lang.ReMatches(xs[0].(*regexp.Regexp), xs[1].(string))
+			}`)
+			return e
+		}(),
+		"re-seq": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 2 {
+					panic("bad number of arguments to re-seq.")
+				}
+				if l := lang.ReSeq(xs[0].(*regexp.Regexp), xs[1].(string)); l != nil {
+					return l
+				}
+				return nil
+			}`)
+			return e
+		}(),
+		"deref": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 1 {
+					panic("bad number of arguments to deref.")
+				}
+				return xs[0].(interface {
+					Deref() interface{}
+				}).Deref()
+			}`)
+			return e
+		}(),
 		"or": func() ast.Expr {
 			e, _ := parser.ParseExpr(`
 			func(xs ...interface{}) interface{} {
@@ -626,6 +1517,138 @@ var Symbols = &SymExprsTable{
 			}`)
 			return e
 		}(),
+		"conj": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) == 0 {
+					return persistent.NewVector()
+				}
+				ret := xs[0]
+				for _, x := range xs[1:] {
+					switch coll := ret.(type) {
+					case *persistent.Vector:
+						ret = coll.Conj(x)
+					case *persistent.SubVector:
+						ret = coll.Conj(x)
+					case *persistent.Queue:
+						ret = coll.Conj(x)
+					case *persistent.HashSet:
+						ret = coll.Conj(x)
+					case *persistent.SortedSet:
+						ret = coll.Conj(x)
+					case *persistent.List:
+						ret = coll.Cons(x)
+					case nil:
+						ret = persistent.NewList(x)
+					default:
+						panic("conj not supported on this type.")
+					}
+				}
+				return ret
+			}`)
+			return e
+		}(),
+		"count": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 1 {
+					panic("bad number of arguments to count.")
+				}
+				switch coll := xs[0].(type) {
+				case *persistent.List:
+					n := 0
+					for ; coll != nil; coll = coll.Rest() {
+						n++
+					}
+					return n
+				case interface {
+					Count() int
+				}:
+					return coll.Count()
+				case string:
+					return len([]rune(coll))
+				case nil:
+					return 0
+				}
+				panic("count not supported on this type.")
+			}`)
+			return e
+		}(),
+		"peek": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 1 {
+					panic("bad number of arguments to peek.")
+				}
+				switch coll := xs[0].(type) {
+				case *persistent.Vector:
+					return coll.Peek()
+				case *persistent.SubVector:
+					return coll.Peek()
+				case *persistent.Queue:
+					return coll.Peek()
+				case *persistent.List:
+					if coll == nil {
+						return nil
+					}
+					return coll.First()
+				case nil:
+					return nil
+				}
+				panic("peek not supported on this type.")
+			}`)
+			return e
+		}(),
+		"pop": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 1 {
+					panic("bad number of arguments to pop.")
+				}
+				switch coll := xs[0].(type) {
+				case *persistent.Vector:
+					return coll.Pop()
+				case *persistent.SubVector:
+					return coll.Pop()
+				case *persistent.Queue:
+					return coll.Pop()
+				case *persistent.List:
+					if coll == nil {
+						panic("can't pop empty list.")
+					}
+					return coll.Rest()
+				case nil:
+					return nil
+				}
+				panic("pop not supported on this type.")
+			}`)
+			return e
+		}(),
+		"subvec": func() ast.Expr {
+			e, _ := parser.ParseExpr(`
+			func(xs ...interface{}) interface{} {
+				if len(xs) != 2 && len(xs) != 3 {
+					panic("bad number of arguments to subvec.")
+				}
+				start := xs[1].(int)
+				switch v := xs[0].(type) {
+				case *persistent.Vector:
+					end := v.Count()
+					if len(xs) == 3 {
+						end = xs[2].(int)
+					}
+					return v.Subvec(start, end)
+				case *persistent.SubVector:
+					end := v.Count()
+					if len(xs) == 3 {
+						end = xs[2].(int)
+					}
+					return v.Subvec(start, end)
+				}
+				panic("subvec not supported on this type.")
+			}`)
+			return e
+		}(),
 		"println": func() ast.Expr {
 			e, _ := parser.ParseExpr(`
 			func(xs ...interface{}) interface{} {
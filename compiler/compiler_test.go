@@ -0,0 +1,301 @@
+package compiler
+
+import (
+	"bytes"
+	"go/printer"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLet(t *testing.T) {
+	out := run(t, `
+		(println (let* [a 1 b (+ a 1) c (+ a b)] [a b c]))
+		(println (let* [a 1 a (+ a 1) a (* a 10)] a))
+		(def x 1)
+		(println (let* [x 2] (let* [x (+ x 1)] x)) x)
+		(println (let* [a-b 1 x? 2 a_b 3 *c* 4 a-b (+ a-b 10)] [a-b x? a_b *c*]))
+		(println (let* [] 5))
+		(println (let* [f (fn* [n] (+ n 1)) n 41] (f n)))`)
+	expected := "[1 2 3]\n20\n3 1\n[11 2 3 4]\n5\n42\n"
+	if out != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out)
+	}
+
+	errCases := []struct {
+		source   string
+		expected string
+	}{
+		{"(let* (a 1) a)", "let* requires a vector for its bindings."},
+		{"(let*)", "let* requires a vector for its bindings."},
+		{"(let* [a] a)", "let* requires an even number of forms in binding vector."},
+		{"(let* [a 1 b] a)", "let* requires an even number of forms in binding vector."},
+		{"(let* [1 2] 1)", "bad binding form, expected symbol, got: 1."},
+		{"(let* [:a 2] 1)", "bad binding form, expected symbol, got: :a."},
+		{"(let* [a/b 2] 1)", "can't let qualified name: a/b."},
+		{"(let* [a b] a)", "Undefined symbol: b"},
+		{"(let* [a 1] b)", "Undefined symbol: b"},
+		{"(do (let* [a 1] a) a)", "Undefined symbol: a"},
+	}
+	for _, c := range errCases {
+		if _, err := compileToString(c.source); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("Case '%s' expected to fail with '%s', got %v.", c.source, c.expected, err)
+		}
+	}
+}
+
+func TestBodies(t *testing.T) {
+	out := run(t, `
+		(println :fn ((fn* [a] (println :a a) (println :b a) (+ a 1)) 1))
+		(println :let (let* [a 1] (println :a a) (def b (+ a 1)) (println :b b) [a b]))
+		(println :nested (let* [f (fn* [] (println :f) (let* [x 1] (println :x) x))] (println :let) (f)))
+		(println :empty (= nil ((fn* [])) (let* [])))`)
+	expected := ":a 1\n:b 1\n:fn 2\n:a 1\n:b 2\n:let [1 2]\n:let\n:f\n:x\n:nested 1\n:empty true\n"
+	if out != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestIf(t *testing.T) {
+	// Only nil and false are logically false.
+	out := run(t, `
+		(println (if nil 1 2) (if false 1 2) (if true 1 2))
+		(println (if 0 1 2) (if 0.0 1 2) (if "" 1 2) (if :a 1 2) (if 'a 1 2))
+		(println (if [] 1 2) (if (list) 1 2) (if {} 1 2) (if #{} 1 2) (if (fn* [] nil) 1 2))
+		(println (if (< 1 2) 1 2) (if (= nil false) 1 2) (= nil (if nil 1)))`)
+	expected := "2 2 1\n1 1 1 1 1\n1 1 1 1 1\n1 2 true\n"
+	if out != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestLoop(t *testing.T) {
+	out := run(t, `
+		(println (loop* [i 0 sum 0] (if (< i 1000000) (recur (+ i 1) (+ sum i)) sum)))
+		(def fs (loop* [i 0 fs []] (if (< i 3) (recur (+ i 1) (conj fs (fn* [] i))) fs)))
+		(println ((peek fs)) ((peek (pop fs))) ((peek (pop (pop fs)))))
+		(println ((fn* [n acc] (if (> n 0) (recur (- n 1) (+ acc n)) acc)) 1000000 0))
+		(println (loop* [a 1 b (+ a 1)] (let* [c (+ a b)] (if (< c 10) (recur b c) c))))
+		(println (loop* [i 0] (do (println :i i) (if (< i 1) (recur (+ i 1)) :done))))`)
+	expected := "499999500000\n2 1 0\n500000500000\n13\n:i 0\n:i 1\n:done\n"
+	if out != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out)
+	}
+
+	errCases := []struct {
+		source   string
+		expected string
+	}{
+		{"(recur 1)", "can only recur from tail position."},
+		{"(loop* [a 1] (+ 1 (recur 2)))", "can only recur from tail position."},
+		{"(loop* [a 1] (if (recur 2) 1 2))", "can only recur from tail position."},
+		{"(loop* [a 1] (let* [b (recur 2)] b))", "can only recur from tail position."},
+		{"(loop* [a 1] (do (recur 2) a))", "can only recur from tail position."},
+		{"(loop* [a 1] (def b (recur 2)))", "can only recur from tail position."},
+		{"(loop* [a 1] [(recur 2)])", "can only recur from tail position."},
+		{"(loop* [a (recur 1)] a)", "can only recur from tail position."},
+		{"(loop* [a 1] (recur))", "mismatched argument count to recur, expected: 1 args, got: 0."},
+		{"(loop* [a 1 b 2] (recur 1 2 3))", "mismatched argument count to recur, expected: 2 args, got: 3."},
+		{"(fn* [a] (recur))", "mismatched argument count to recur, expected: 1 args, got: 0."},
+		{"(loop* (a 1) a)", "loop* requires a vector for its bindings."},
+		{"(loop* [a] a)", "loop* requires an even number of forms in binding vector."},
+		{"(loop* [1 1] 1)", "bad binding form, expected symbol, got: 1."},
+	}
+	for _, c := range errCases {
+		if _, err := compileToString(c.source); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("Case '%s' expected to fail with '%s', got %v.", c.source, c.expected, err)
+		}
+	}
+}
+
+func TestFnArities(t *testing.T) {
+	out := run(t, `
+		(def f (fn* ([] :none) ([a] [:one a]) ([a b] [:two a b]) ([a b c & more] [:many a b c more])))
+		(println (f) (f 1) (f 1 2) (f 1 2 3 4 5))
+		(println (= nil ((fn* [& xs] xs))) ((fn* [& xs] xs) 1 2) ((fn* [a & xs] [a xs]) 1 2 3))
+		(def g (fn* ([a] [:fixed a]) ([a & xs] [:variadic a xs])))
+		(println (g 1) (g 1 2))
+		(println ((fn* [a & xs] (if (< a 3) (recur (+ a 1) (conj xs a)) [a xs])) 0 :x))`)
+	expected := ":none [:one 1] [:two 1 2] [:many 1 2 3 (4 5)]\n" +
+		"true (1 2) [1 (2 3)]\n" +
+		"[:fixed 1] [:variadic 1 (2)]\n" +
+		"[3 (2 1 0 :x)]\n"
+	if out != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out)
+	}
+
+	arityCases := []struct {
+		source   string
+		expected string
+	}{
+		{"((fn* [a] a))", "ArityException 0 fn\n"},
+		{"((fn* [a] a) 1 2)", "ArityException 2 fn\n"},
+		{"((fn* ([] 1) ([a b] 2)) 1)", "ArityException 1 fn\n"},
+		{"((fn* [a b & xs] a) 1)", "ArityException 1 fn\n"},
+	}
+	for _, c := range arityCases {
+		if out := run(t, c.source); out != c.expected {
+			t.Errorf("Case '%s' expected to print '%s', printed '%s'.", c.source, c.expected, out)
+		}
+	}
+
+	errCases := []struct {
+		source   string
+		expected string
+	}{
+		{"(fn* ([& xs] 1) ([a & xs] 2))", "can't have more than 1 variadic overload."},
+		{"(fn* ([a] 1) ([b] 2))", "can't have 2 overloads with same arity."},
+		{"(fn* ([a b c] 1) ([a & xs] 2))", "can't have fixed arity function with more params than variadic function."},
+		{"(fn* ([a & xs] 2) ([a b c] 1))", "can't have fixed arity function with more params than variadic function."},
+		{"(fn*)", "fn* requires a parameter vector or a list of arities."},
+		{"(fn* 1)", "fn* requires a parameter vector or a list of arities."},
+		{"(fn* [a &] a)", "fn* must have exactly one parameter after &."},
+		{"(fn* [a & b c] a)", "fn* must have exactly one parameter after &."},
+		{"(fn* [a & &] a)", "fn* params must be unqualified symbols, got: &."},
+		{"(fn* [a/b] 1)", "fn* params must be unqualified symbols, got: a/b."},
+		{"(fn* [1] 1)", "fn* params must be unqualified symbols, got: 1."},
+	}
+	for _, c := range errCases {
+		if _, err := compileToString(c.source); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("Case '%s' expected to fail with '%s', got %v.", c.source, c.expected, err)
+		}
+	}
+}
+
+func TestNamedFn(t *testing.T) {
+	out := run(t, `
+		(def fact (fn* fact [n] (if (< n 2) 1 (* n (fact (- n 1))))))
+		(println (fact 10))
+		(println ((fn* fib ([n] (fib 0 1 n)) ([a b n] (if (= n 0) a (fib b (+ a b) (- n 1))))) 10))
+		(println (let* [g 1] ((fn* g ([] (g :self)) ([x] x)))) ((fn* g [g] g) 1))`)
+	expected := "3628800\n55\n:self 1\n"
+	if out != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out)
+	}
+
+	cases := []struct {
+		source   string
+		expected string
+	}{
+		{"((fn* fact [n] n))", "ArityException 0 fact\n"},
+		{"((fn* my-fn ([] 1) ([a b] 2)) 1)", "ArityException 1 my-fn\n"},
+		{"((fn* rec [n] (if (= n 0) (rec) (rec (- n 1)))) 2)", "ArityException 0 rec\n"},
+	}
+	for _, c := range cases {
+		if out := run(t, c.source); out != c.expected {
+			t.Errorf("Case '%s' expected to print '%s', printed '%s'.", c.source, c.expected, out)
+		}
+	}
+	if _, err := compileToString("(fn* a/b [] 1)"); err == nil || !strings.Contains(err.Error(), "fn* name must be an unqualified symbol, got: a/b.") {
+		t.Errorf("Qualified fn* name expected to fail, got %v.", err)
+	}
+}
+
+func TestVar(t *testing.T) {
+	for _, source := range []string{"(def x 1)\n(println #'x)", "(def x 1)\n(println (var x))"} {
+		_, err := CompileString(source)
+		cerr, ok := err.(*Error)
+		if !ok || cerr.Line != 2 || cerr.Err.Error() != "var is not supported, refer to the symbol itself." {
+			t.Errorf("Case '%s' expected to fail at line 2 for using var, got %v.", source, err)
+		}
+	}
+}
+
+func TestLetMangling(t *testing.T) {
+	src, err := compileToString("(let* [a-b 1 x? 2 a-b 3] [a-b x?])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Locals are Go variables named after them, with a number to tell apart
+	// the ones that shadow others.
+	for _, local := range []string{"a_b__", "x___"} {
+		if !strings.Contains(src, local) {
+			t.Errorf("Expected a local named like %s in:\n%s", local, src)
+		}
+	}
+}
+
+func TestRegexpLiterals(t *testing.T) {
+	src, err := compileToString(`
+		(println #"a+" #"b" #"a+")
+		(def f (fn* [] #"b"))
+		(println (f) #"a\d")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each distinct pattern is compiled once, into a package variable.
+	for _, decl := range []string{
+		`var regexp0 = regexp.MustCompile("a+")`,
+		`var regexp1 = regexp.MustCompile("b")`,
+		`var regexp2 = regexp.MustCompile("a\\d")`,
+	} {
+		if !strings.Contains(src, decl) {
+			t.Errorf("Expected declaration '%s' in:\n%s", decl, src)
+		}
+	}
+	if n := strings.Count(src, "regexp.MustCompile("); n != 3 {
+		t.Errorf("Expected 3 regexps compiled, got %d in:\n%s", n, src)
+	}
+	for _, use := range []string{"(regexp0, regexp1, regexp0)", "return regexp1", "regexp2)"} {
+		if !strings.Contains(src, use) {
+			t.Errorf("Expected '%s' in:\n%s", use, src)
+		}
+	}
+}
+
+// Compiles Gojure source code, giving the Go source code for it.
+func compileToString(s string) (string, error) {
+	f, err := CompileString(s)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, token.NewFileSet(), f); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Compiles and runs Gojure source code, giving what it prints. If it panics, what
+// it panicked with is printed last.
+func run(t *testing.T, s string) string {
+	t.Helper()
+	src, err := compileToString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go tool not found.")
+	}
+	src = strings.Replace(src, "func main() {", "func gojureMain() {", 1) + `
+		func main() {
+			defer func() {
+				switch r := recover().(type) {
+				case nil:
+				case *lang.ArityException:
+					fmt.Printf("ArityException %d %s\n", r.Actual, r.Name)
+				default:
+					fmt.Println("panic:", r)
+				}
+			}()
+			gojureMain()
+		}`
+	// Inside the package, so that the program can import this repository's
+	// packages; the underscore keeps it out of ./... patterns.
+	dir, err := os.MkdirTemp(".", "_run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("go", "run", "./"+dir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s\nin:\n%s", err, out, src)
+	}
+	return string(out)
+}
@@ -0,0 +1,137 @@
+package lang
+
+import (
+	"math/big"
+	"strings"
+)
+
+// A BigDecimal is an arbitrary-precision decimal number, as read from literals
+// ending in M. Its value is unscaled × 10^-scale; thus 1.50M has unscaled value
+// 150 and scale 2. BigDecimals with the same value but different scale are
+// equivalent.
+// A BigDecimal value is immutable.
+type BigDecimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// Makes a new BigDecimal with value unscaled × 10^-scale.
+func NewBigDecimal(unscaled *big.Int, scale int) *BigDecimal {
+	return &BigDecimal{new(big.Int).Set(unscaled), scale}
+}
+
+// Gives the unscaled value of d.
+func (d *BigDecimal) Unscaled() *big.Int {
+	return new(big.Int).Set(d.unscaled)
+}
+
+// Gives the scale of d, ie. the number of digits after its decimal point.
+func (d *BigDecimal) Scale() int {
+	return d.scale
+}
+
+// Gives the exact value of d as a ratio.
+func (d *BigDecimal) Rat() *big.Rat {
+	ret := new(big.Rat).SetInt(d.unscaled)
+	if d.scale > 0 {
+		return ret.Quo(ret, new(big.Rat).SetInt(pow10(d.scale)))
+	}
+	return ret.Mul(ret, new(big.Rat).SetInt(pow10(-d.scale)))
+}
+
+// Gives d with its unscaled value rescaled to scale, which must not be smaller
+// than d's.
+func (d *BigDecimal) rescale(scale int) *big.Int {
+	return new(big.Int).Mul(d.unscaled, pow10(scale-d.scale))
+}
+
+// Gives the equivalent of d with the smallest scale, so that equivalent
+// BigDecimals are given the same.
+func (d *BigDecimal) stripZeros() *BigDecimal {
+	unscaled, scale := new(big.Int).Set(d.unscaled), d.scale
+	if unscaled.Sign() == 0 {
+		return &BigDecimal{unscaled, 0}
+	}
+	ten, rem := big.NewInt(10), new(big.Int)
+	for {
+		q, r := new(big.Int).QuoRem(unscaled, ten, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		unscaled, scale = q, scale-1
+	}
+	return &BigDecimal{unscaled, scale}
+}
+
+// Gives -1, 0 or 1 depending on whether d is less than, equal to or greater than
+// other.
+func (d *BigDecimal) Cmp(other *BigDecimal) int {
+	a, b := d.unscaled, other.unscaled
+	if d.scale < other.scale {
+		a = d.rescale(other.scale)
+	} else if d.scale > other.scale {
+		b = other.rescale(d.scale)
+	}
+	return a.Cmp(b)
+}
+
+func (d *BigDecimal) String() string {
+	if d.scale <= 0 {
+		return new(big.Int).Mul(d.unscaled, pow10(-d.scale)).String()
+	}
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if len(digits) <= d.scale {
+		digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+	}
+	s := digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	if d.unscaled.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+func addDecimals(a, b *BigDecimal) *BigDecimal {
+	if a.scale < b.scale {
+		return &BigDecimal{new(big.Int).Add(a.rescale(b.scale), b.unscaled), b.scale}
+	}
+	return &BigDecimal{new(big.Int).Add(a.unscaled, b.rescale(a.scale)), a.scale}
+}
+
+func multiplyDecimals(a, b *BigDecimal) *BigDecimal {
+	return &BigDecimal{new(big.Int).Mul(a.unscaled, b.unscaled), a.scale + b.scale}
+}
+
+func negateDecimal(d *BigDecimal) *BigDecimal {
+	return &BigDecimal{new(big.Int).Neg(d.unscaled), d.scale}
+}
+
+// Gives the exact decimal representation of r, with at least minScale digits after
+// its decimal point. It panics if there is none, as with 1/3.
+func ratToDecimal(r *big.Rat, minScale int) *BigDecimal {
+	rest, rem := new(big.Int).Set(r.Denom()), new(big.Int)
+	for _, f := range []*big.Int{big.NewInt(2), big.NewInt(5)} {
+		for {
+			q, m := new(big.Int).QuoRem(rest, f, rem)
+			if m.Sign() != 0 {
+				break
+			}
+			rest = q
+		}
+	}
+	if rest.Cmp(big.NewInt(1)) != 0 {
+		panic(nonTerminatingDecimal)
+	}
+	if minScale < 0 {
+		minScale = 0
+	}
+	for scale := minScale; ; scale++ {
+		q, m := new(big.Int).QuoRem(new(big.Int).Mul(r.Num(), pow10(scale)), r.Denom(), rem)
+		if m.Sign() == 0 {
+			return &BigDecimal{q, scale}
+		}
+	}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
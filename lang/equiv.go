@@ -0,0 +1,322 @@
+package lang
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"time"
+
+	pers "github.com/tcard/gojure/persistent"
+)
+
+// An Equiver decides by itself whether it is equivalent to other value, as by
+// Equiv. Equivers should also be Hashers.
+type Equiver = pers.Equiver
+
+// A Hasher gives its own hash code, as by Hash. Values that are equivalent must
+// have the same hash code.
+type Hasher = pers.Hasher
+
+// Equality is the github.com/tcard/gojure/persistent#Equality of Gojure's hash
+// maps and sets, which hash and compare by Hash and Equiv.
+type Equality struct{}
+
+func (Equality) Hash(x interface{}) uint32 {
+	return Hash(x)
+}
+
+func (Equality) Equiv(a, b interface{}) bool {
+	return Equiv(a, b)
+}
+
+// Makes a new hash map from keys and values interleaved, as by
+// persistent.NewHashMap, with Gojure's Equality.
+func NewHashMap(keyvals ...interface{}) *pers.HashMap {
+	return pers.NewHashMapWith(Equality{}, keyvals...)
+}
+
+// Makes a new hash set containing these items, as by persistent.NewHashSet, with
+// Gojure's Equality.
+func NewHashSet(items ...interface{}) *pers.HashSet {
+	return pers.NewHashSetWith(Equality{}, items...)
+}
+
+// Tells whether a and b are equivalent, as Clojure's =.
+//
+// Numbers are equivalent if they have the same value and are in the same
+// category (integers, ratios, decimals or floating-point numbers), regardless of
+// their Go type.
+// Sequential collections (lists, vectors, seqs, queues) are equivalent if they
+// have equivalent elements in the same order, maps if they have the same keys
+// with equivalent values, and sets if they have the same elements. Symbols and
+// keywords are equivalent if they have the same namespace and name, and instants
+// (time.Time) if they are the same instant, whatever their location. Values
+// implementing Equiver decide by themselves. Otherwise, Go's equality is used.
+func Equiv(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if e, ok := a.(Equiver); ok {
+		return e.Equiv(b)
+	}
+	if e, ok := b.(Equiver); ok {
+		return e.Equiv(a)
+	}
+	if eq, ok := numberEquiv(a, b); ok {
+		return eq
+	}
+	if na, ok := sequential(a); ok {
+		nb, ok := sequential(b)
+		if !ok {
+			return false
+		}
+		for {
+			xa, moreA := na()
+			xb, moreB := nb()
+			if moreA != moreB {
+				return false
+			}
+			if !moreA {
+				return true
+			}
+			if !Equiv(xa, xb) {
+				return false
+			}
+		}
+	}
+	if ea, ok := mapEntries(a); ok {
+		if _, ok := mapEntries(b); !ok || count(a) != count(b) {
+			return false
+		}
+		for k, v, more := ea(); more; k, v, more = ea() {
+			bv, ok := mapGet(b, k)
+			if !ok || !Equiv(v, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	if ia, ok := setItems(a); ok {
+		if _, ok := setItems(b); !ok || count(a) != count(b) {
+			return false
+		}
+		for x, more := ia(); more; x, more = ia() {
+			if !setContains(b, x) {
+				return false
+			}
+		}
+		return true
+	}
+	if sa, ok := a.(Symbol); ok {
+		sb, ok := b.(Symbol)
+		return ok && sa.NS == sb.NS && sa.Name == sb.Name
+	}
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if !ta.Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+// Gives a hash code for x, consistent with Equiv: equivalent values have the same
+// hash code. Values implementing Hasher give their own.
+func Hash(x interface{}) uint32 {
+	if x == nil {
+		return 0
+	}
+	if h, ok := x.(Hasher); ok {
+		return h.Hash()
+	}
+	if h, ok := numberHash(x); ok {
+		return h
+	}
+	switch v := x.(type) {
+	case string:
+		return hashString(v)
+	case Keyword:
+		return hashString(string(v)) + 0x9e3779b9
+	case Symbol:
+		return hashString(v.String()) ^ 0x9e3779b9
+	case Char:
+		return hashUint64(uint64(v)) + 0x9e3779b9
+	case time.Time:
+		return hashUint64(uint64(v.UnixNano()))
+	}
+	if next, ok := sequential(x); ok {
+		h := uint32(1)
+		for x, more := next(); more; x, more = next() {
+			h = 31*h + Hash(x)
+		}
+		return h
+	}
+	if next, ok := mapEntries(x); ok {
+		h := uint32(0)
+		for k, v, more := next(); more; k, v, more = next() {
+			h += Hash(k) ^ Hash(v)
+		}
+		return h
+	}
+	if next, ok := setItems(x); ok {
+		h := uint32(0)
+		for x, more := next(); more; x, more = next() {
+			h += Hash(x)
+		}
+		return h
+	}
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1231
+		}
+		return 1237
+	case reflect.String:
+		return hashString(v.String())
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return hashUint64(uint64(v.Pointer()))
+	}
+	return hashString(fmt.Sprintf("%#v", x))
+}
+
+// Gives a function that yields, one by one, the elements of x if it is a
+// sequential collection.
+func sequential(x interface{}) (func() (interface{}, bool), bool) {
+	switch v := x.(type) {
+	case *pers.List:
+		return func() (interface{}, bool) {
+			if v == nil {
+				return nil, false
+			}
+			first := v.First()
+			v = v.Rest()
+			return first, true
+		}, true
+	case *pers.Queue:
+		it := v.Iterator()
+		return func() (interface{}, bool) {
+			if !it.Next() {
+				return nil, false
+			}
+			return it.Item(), true
+		}, true
+	case interface {
+		Count() int
+		Nth(int) interface{}
+	}:
+		i := 0
+		return func() (interface{}, bool) {
+			if i >= v.Count() {
+				return nil, false
+			}
+			i++
+			return v.Nth(i - 1), true
+		}, true
+	case Seq:
+		return func() (interface{}, bool) {
+			if isNil(v) {
+				return nil, false
+			}
+			first := v.First()
+			v = v.Rest()
+			return first, true
+		}, true
+	}
+	return nil, false
+}
+
+// Gives a function that yields, one by one, the entries of x if it is a map.
+func mapEntries(x interface{}) (func() (interface{}, interface{}, bool), bool) {
+	switch v := x.(type) {
+	case *pers.HashMap:
+		it := v.Iterator()
+		return func() (interface{}, interface{}, bool) {
+			more := it.Next()
+			return it.Key(), it.Val(), more
+		}, true
+	case *pers.SortedMap:
+		it := v.Iterator()
+		return func() (interface{}, interface{}, bool) {
+			if !it.Next() {
+				return nil, nil, false
+			}
+			return it.Key(), it.Val(), true
+		}, true
+	}
+	return nil, false
+}
+
+func mapGet(m interface{}, k interface{}) (interface{}, bool) {
+	switch v := m.(type) {
+	case *pers.HashMap:
+		return v.Get(k)
+	case *pers.SortedMap:
+		return v.Get(k)
+	}
+	return nil, false
+}
+
+// Gives a function that yields, one by one, the items of x if it is a set.
+func setItems(x interface{}) (func() (interface{}, bool), bool) {
+	switch v := x.(type) {
+	case *pers.HashSet:
+		it := v.Iterator()
+		return func() (interface{}, bool) {
+			more := it.Next()
+			return it.Item(), more
+		}, true
+	case *pers.SortedSet:
+		it := v.Iterator()
+		return func() (interface{}, bool) {
+			if !it.Next() {
+				return nil, false
+			}
+			return it.Item(), true
+		}, true
+	}
+	return nil, false
+}
+
+func setContains(s interface{}, x interface{}) bool {
+	switch v := s.(type) {
+	case *pers.HashSet:
+		return v.Contains(x)
+	case *pers.SortedSet:
+		return v.Contains(x)
+	}
+	return false
+}
+
+// Tells whether x is nil, or a nil value of a type that can be nil.
+func isNil(x interface{}) bool {
+	if x == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(x); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func count(coll interface{}) int {
+	return coll.(interface {
+		Count() int
+	}).Count()
+}
+
+func hashString(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func hashUint64(n uint64) uint32 {
+	return uint32(n) ^ uint32(n>>32)
+}
@@ -0,0 +1,125 @@
+package lang
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	pers "github.com/tcard/gojure/persistent"
+)
+
+// A valueSeq is a Seq that is not a pointer, over the items in a slice.
+type valueSeq struct {
+	items []interface{}
+}
+
+func (s valueSeq) First() interface{} {
+	return s.items[0]
+}
+
+func (s valueSeq) Rest() Seq {
+	if len(s.items) == 1 {
+		return nil
+	}
+	return valueSeq{s.items[1:]}
+}
+
+func (s valueSeq) Cons(x interface{}) Seq {
+	return valueSeq{append([]interface{}{x}, s.items...)}
+}
+
+func TestEquiv(t *testing.T) {
+	sym := Symbol{NS: "a", Name: "b"}
+	inst := MustParseInst("1985-04-12T23:20:50.52Z")
+	now := time.Now()
+	cases := []struct {
+		name     string
+		a, b     interface{}
+		expected bool
+	}{
+		{"nil", nil, nil, true},
+		{"nil and false", nil, false, false},
+		{"nil and empty list", nil, pers.NewList(), false},
+		{"empty list and vector", pers.NewList(), pers.NewVector(), true},
+		{"nil and empty vector", nil, pers.NewVector(), false},
+		{"strings", "a", "a", true},
+		{"different strings", "a", "b", false},
+		{"string and keyword", "a", Keyword("a"), false},
+		{"keywords", Keyword("a/b"), Keyword("a/b"), true},
+		{"symbols", sym, Symbol{NS: "a", Name: "b"}, true},
+		{"symbols with meta", sym.WithMeta(NewHashMap(Keyword("a"), 1)), sym, true},
+		{"different symbols", sym, Symbol{Name: "b"}, false},
+		{"chars", Char('a'), Char('a'), true},
+		{"char and string", Char('a'), "a", false},
+		{"insts", inst, MustParseInst("1985-04-12T23:20:50.520Z"), true},
+		{"insts in other locations", inst, MustParseInst("1985-04-13T00:20:50.52+01:00"), true},
+		{"inst in local time", inst, inst.Local(), true},
+		{"inst with monotonic clock", now, now.Round(0), true},
+		{"different insts", inst, inst.Add(time.Nanosecond), false},
+		{"ints", 1, int64(1), true},
+		{"int and big int", 1, big.NewInt(1), true},
+		{"int and float", 1, 1.0, false},
+		{"list and vector", pers.NewList(1, 2), pers.NewVector(1, 2), true},
+		{"vector and subvector", pers.NewVector(1, 2), pers.NewVector(0, 1, 2).Subvec(1, 3), true},
+		{"vector and queue", pers.NewVector(1, 2), pers.NewQueue(1, 2), true},
+		{"vector and seq", pers.NewVector(1, 2), valueSeq{[]interface{}{1, 2}}, true},
+		{"vector and shorter seq", pers.NewVector(1, 2), valueSeq{[]interface{}{1}}, false},
+		{"vector and lazy seq", pers.NewVector(1, 2), Take(2, NewList(1, 2, 3)), true},
+		{"nested", pers.NewVector(pers.NewList(1)), pers.NewList(pers.NewVector(int64(1))), true},
+		{"vector and map", pers.NewVector(), NewHashMap(), false},
+		{"different order", pers.NewVector(1, 2), pers.NewVector(2, 1), false},
+		{"maps", NewHashMap(1, 2, 3, 4), NewHashMap(3, 4, 1, 2), true},
+		{"maps with collection keys", NewHashMap(pers.NewVector(1), 2), NewHashMap(pers.NewList(1), int64(2)), true},
+		{"different maps", NewHashMap(1, 2), NewHashMap(1, 3), false},
+		{"maps of different size", NewHashMap(1, 2), NewHashMap(1, 2, 3, 4), false},
+		{"hash and sorted maps", NewHashMap(1, 2), pers.NewSortedMap(Compare, 1, 2), true},
+		{"sets", NewHashSet(1, 2), NewHashSet(2, 1), true},
+		{"hash and sorted sets", NewHashSet(1, 2), pers.NewSortedSet(Compare, 2, 1), true},
+		{"different sets", NewHashSet(1, 2), NewHashSet(1, 3), false},
+		{"set and map", NewHashSet(), NewHashMap(), false},
+	}
+	for _, c := range cases {
+		for _, pair := range [][2]interface{}{{c.a, c.b}, {c.b, c.a}} {
+			if got := Equiv(pair[0], pair[1]); got != c.expected {
+				t.Errorf("Case '%s': Equiv(%v, %v) expected to be %v.", c.name, pair[0], pair[1], c.expected)
+			}
+		}
+		if c.expected && Hash(c.a) != Hash(c.b) {
+			t.Errorf("Case '%s': %v and %v are equivalent, but hash to %d and %d.", c.name, c.a, c.b, Hash(c.a), Hash(c.b))
+		}
+	}
+}
+
+func TestEquality(t *testing.T) {
+	m := NewHashMap(1, "int", pers.NewVector(1, 2), "vector", Symbol{Name: "a"}, "symbol")
+	cases := []struct {
+		key      interface{}
+		expected interface{}
+	}{
+		{int64(1), "int"},
+		{big.NewInt(1), "int"},
+		{pers.NewList(1, 2), "vector"},
+		{Symbol{Name: "a"}.WithMeta(NewHashMap(Keyword("b"), 1)), "symbol"},
+	}
+	inst := MustParseInst("2000-01-01T00:00:00Z")
+	m = m.Assoc(inst, "inst")
+	cases = append(cases, struct {
+		key      interface{}
+		expected interface{}
+	}{inst.In(time.FixedZone("X", 3600)), "inst"})
+	for _, c := range cases {
+		if got, ok := m.Get(c.key); !ok || got != c.expected {
+			t.Errorf("Key %v expected to map to %v, got %v %v.", c.key, c.expected, got, ok)
+		}
+	}
+	if s := NewHashSet(1, int64(1), 1.0); s.Count() != 2 {
+		t.Errorf("Set %v expected to have two items.", s)
+	}
+	if m := m.Assoc(int8(1), "int8"); m.Count() != 4 {
+		t.Errorf("Map %v expected to keep its Equality on assoc.", m)
+	}
+	// Maps made by package persistent use Go's equality.
+	if _, ok := pers.NewHashMap(1, 2).Get(int64(1)); ok {
+		t.Errorf("Map made with Go's equality expected not to find int64(1) for 1.")
+	}
+}
@@ -0,0 +1,62 @@
+package lang
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An RFC 3339 timestamp, where everything after the year is optional.
+var instPattern = regexp.MustCompile(`^(\d{4})(?:-(\d{2})(?:-(\d{2})(?:T(\d{2})(?::(\d{2})(?::(\d{2})(?:\.(\d{1,9}))?)?)?)?)?)?(Z|[-+]\d{2}:\d{2})?$`)
+
+var badInst = errors.New("timestamp must be of the form yyyy-mm-ddThh:mm:ss.fffffffff+hh:mm, where everything after the year is optional")
+
+// Parses a timestamp as in #inst literals: an RFC 3339 timestamp, as in
+// "1985-04-12T23:20:50.52Z", of which everything after the year may be left out.
+// Timestamps without an offset are in UTC.
+func ParseInst(s string) (time.Time, error) {
+	m := instPattern.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, badInst
+	}
+	field := func(i, def int) int {
+		if m[i] == "" {
+			return def
+		}
+		n, _ := strconv.Atoi(m[i])
+		return n
+	}
+	loc := time.UTC
+	if offset := m[8]; offset != "" && offset != "Z" {
+		h, _ := strconv.Atoi(offset[1:3])
+		min, _ := strconv.Atoi(offset[4:])
+		secs := (h*60 + min) * 60
+		if offset[0] == '-' {
+			secs = -secs
+		}
+		loc = time.FixedZone("", secs)
+	}
+	nsec := 0
+	if m[7] != "" {
+		nsec, _ = strconv.Atoi(m[7] + strings.Repeat("0", 9-len(m[7])))
+	}
+	year, month, day := field(1, 0), field(2, 1), field(3, 1)
+	hour, min, sec := field(4, 0), field(5, 0), field(6, 0)
+	t := time.Date(year, time.Month(month), day, hour, min, sec, nsec, loc)
+	// time.Date normalizes fields out of their range, like a 13th month.
+	if t.Month() != time.Month(month) || t.Day() != day || t.Hour() != hour || t.Minute() != min || t.Second() != sec {
+		return time.Time{}, badInst
+	}
+	return t, nil
+}
+
+// Like ParseInst, but panics if s is not a valid timestamp.
+func MustParseInst(s string) time.Time {
+	t, err := ParseInst(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
@@ -1,10 +1,52 @@
 package lang
 
+import (
+	"strings"
+
+	pers "github.com/tcard/gojure/persistent"
+)
+
+// A Keyword is a symbolic identifier that evaluates to itself. Keywords with a
+// namespace hold it before a '/' separator, as in "ns/name".
 type Keyword string
 
+// Gives the name part of the keyword, without its namespace.
+func (k Keyword) Name() string {
+	if i := strings.Index(string(k), "/"); i >= 0 {
+		return string(k)[i+1:]
+	}
+	return string(k)
+}
+
+// Gives the namespace part of the keyword, or "" if it has none.
+func (k Keyword) Namespace() string {
+	if i := strings.Index(string(k), "/"); i >= 0 {
+		return string(k)[:i]
+	}
+	return ""
+}
+
+func (k Keyword) String() string {
+	return ":" + string(k)
+}
+
+// A Symbol is an identifier, optionally namespace-qualified. Symbols may carry
+// metadata, which doesn't take part in their equivalence.
 type Symbol struct {
 	NS   string
 	Name string
+	meta *pers.HashMap
+}
+
+// Gives the metadata attached to the symbol, or nil if it has none.
+func (s Symbol) Meta() *pers.HashMap {
+	return s.meta
+}
+
+// Gives a symbol with the same namespace and name as s and meta as its metadata.
+func (s Symbol) WithMeta(meta *pers.HashMap) Symbol {
+	s.meta = meta
+	return s
 }
 
 func (s Symbol) String() string {
@@ -13,3 +55,11 @@ func (s Symbol) String() string {
 	}
 	return s.Name
 }
+
+// A Char is a single Unicode character, as read from literals like \a or
+// \newline.
+type Char rune
+
+func (c Char) String() string {
+	return string(rune(c))
+}
@@ -0,0 +1,565 @@
+package lang
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Gojure numbers are Go ints, *big.Int for arbitrary-precision integers (literals
+// ending in N), *big.Rat for ratios like 1/3, float64 for floating-point numbers
+// and *BigDecimal for arbitrary-precision decimals (literals ending in M). Other
+// Go numeric types are taken as the closest of those.
+//
+// Arithmetic follows Clojure's contagion rules: the result of an operation is of
+// the widest type among its operands, in the order int, *big.Int, *big.Rat,
+// *BigDecimal, float64. Ratios with denominator 1 become *big.Int.
+
+var (
+	integerOverflow       = errors.New("integer overflow")
+	divideByZero          = errors.New("divide by zero")
+	nonTerminatingDecimal = errors.New("non-terminating decimal expansion; no exact representable decimal result")
+)
+
+// Kinds of numbers, from narrowest to widest as by contagion rules.
+type numberOps int
+
+const (
+	notANumber numberOps = iota
+	intOps
+	bigIntOps
+	ratioOps
+	decimalOps
+	floatOps
+)
+
+// Gives x as one of int, *big.Int, *big.Rat, *BigDecimal or float64, and which
+// of them it is. Ratios with denominator 1 are given as *big.Int.
+func number(x interface{}) (interface{}, numberOps) {
+	switch v := x.(type) {
+	case int:
+		return v, intOps
+	case *big.Int:
+		return v, bigIntOps
+	case *big.Rat:
+		if v.IsInt() {
+			return v.Num(), bigIntOps
+		}
+		return v, ratioOps
+	case *BigDecimal:
+		return v, decimalOps
+	case float64:
+		return v, floatOps
+	case Char:
+		return v, notANumber
+	}
+	if x == nil {
+		return nil, notANumber
+	}
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < math.MinInt || v.Int() > math.MaxInt {
+			return big.NewInt(v.Int()), bigIntOps
+		}
+		return int(v.Int()), intOps
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt {
+			return new(big.Int).SetUint64(v.Uint()), bigIntOps
+		}
+		return int(v.Uint()), intOps
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), floatOps
+	}
+	return x, notANumber
+}
+
+// Tells whether x is a number.
+func IsNumber(x interface{}) bool {
+	_, ops := number(x)
+	return ops != notANumber
+}
+
+// Gives x and y as numbers of the same type, as by contagion rules, and which
+// type that is. It panics if any of them is not a number.
+func coerce(x, y interface{}) (interface{}, interface{}, numberOps) {
+	x, xops := number(x)
+	y, yops := number(y)
+	if xops == notANumber {
+		panic(fmt.Errorf("not a number: %v", x))
+	}
+	if yops == notANumber {
+		panic(fmt.Errorf("not a number: %v", y))
+	}
+	ops := xops
+	if yops > ops {
+		ops = yops
+	}
+	return toOps(x, ops), toOps(y, ops), ops
+}
+
+func toOps(x interface{}, ops numberOps) interface{} {
+	switch ops {
+	case bigIntOps:
+		return toBigInt(x)
+	case ratioOps:
+		return toRat(x)
+	case decimalOps:
+		return toDecimal(x)
+	case floatOps:
+		return toFloat(x)
+	}
+	return x
+}
+
+func toBigInt(x interface{}) *big.Int {
+	switch v := x.(type) {
+	case int:
+		return big.NewInt(int64(v))
+	case *big.Int:
+		return v
+	}
+	panic(fmt.Errorf("can't convert %v to integer", x))
+}
+
+func toRat(x interface{}) *big.Rat {
+	switch v := x.(type) {
+	case int:
+		return new(big.Rat).SetInt64(int64(v))
+	case *big.Int:
+		return new(big.Rat).SetInt(v)
+	case *big.Rat:
+		return v
+	case *BigDecimal:
+		return v.Rat()
+	}
+	panic(fmt.Errorf("can't convert %v to ratio", x))
+}
+
+func toDecimal(x interface{}) *BigDecimal {
+	switch v := x.(type) {
+	case int:
+		return &BigDecimal{big.NewInt(int64(v)), 0}
+	case *big.Int:
+		return &BigDecimal{v, 0}
+	case *big.Rat:
+		return ratToDecimal(v, 0)
+	case *BigDecimal:
+		return v
+	}
+	panic(fmt.Errorf("can't convert %v to decimal", x))
+}
+
+func toFloat(x interface{}) float64 {
+	switch v := x.(type) {
+	case int:
+		return float64(v)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f
+	case *BigDecimal:
+		f, _ := v.Rat().Float64()
+		return f
+	case float64:
+		return v
+	}
+	panic(fmt.Errorf("can't convert %v to float", x))
+}
+
+// Gives a ratio as such, or as a *big.Int if its denominator is 1.
+func normalizeRat(r *big.Rat) interface{} {
+	if r.IsInt() {
+		return new(big.Int).Set(r.Num())
+	}
+	return r
+}
+
+// Gives x + y. It panics if both are ints and the result overflows.
+func Add(x, y interface{}) interface{} {
+	return add(x, y, false)
+}
+
+// Gives x + y. If both are ints and the result overflows, it is given as a
+// *big.Int.
+func AddP(x, y interface{}) interface{} {
+	return add(x, y, true)
+}
+
+func add(x, y interface{}, promote bool) interface{} {
+	x, y, ops := coerce(x, y)
+	switch ops {
+	case intOps:
+		a, b := x.(int), y.(int)
+		ret := a + b
+		if (ret^a)&(ret^b) < 0 {
+			if !promote {
+				panic(integerOverflow)
+			}
+			return new(big.Int).Add(big.NewInt(int64(a)), big.NewInt(int64(b)))
+		}
+		return ret
+	case bigIntOps:
+		return new(big.Int).Add(x.(*big.Int), y.(*big.Int))
+	case ratioOps:
+		return normalizeRat(new(big.Rat).Add(x.(*big.Rat), y.(*big.Rat)))
+	case decimalOps:
+		return addDecimals(x.(*BigDecimal), y.(*BigDecimal))
+	}
+	return x.(float64) + y.(float64)
+}
+
+// Gives x - y. It panics if both are ints and the result overflows.
+func Subtract(x, y interface{}) interface{} {
+	return subtract(x, y, false)
+}
+
+// Gives x - y. If both are ints and the result overflows, it is given as a
+// *big.Int.
+func SubtractP(x, y interface{}) interface{} {
+	return subtract(x, y, true)
+}
+
+func subtract(x, y interface{}, promote bool) interface{} {
+	x, y, ops := coerce(x, y)
+	switch ops {
+	case intOps:
+		a, b := x.(int), y.(int)
+		ret := a - b
+		if (a^b)&(a^ret) < 0 {
+			if !promote {
+				panic(integerOverflow)
+			}
+			return new(big.Int).Sub(big.NewInt(int64(a)), big.NewInt(int64(b)))
+		}
+		return ret
+	case bigIntOps:
+		return new(big.Int).Sub(x.(*big.Int), y.(*big.Int))
+	case ratioOps:
+		return normalizeRat(new(big.Rat).Sub(x.(*big.Rat), y.(*big.Rat)))
+	case decimalOps:
+		return addDecimals(x.(*BigDecimal), negateDecimal(y.(*BigDecimal)))
+	}
+	return x.(float64) - y.(float64)
+}
+
+// Gives x * y. It panics if both are ints and the result overflows.
+func Multiply(x, y interface{}) interface{} {
+	return multiply(x, y, false)
+}
+
+// Gives x * y. If both are ints and the result overflows, it is given as a
+// *big.Int.
+func MultiplyP(x, y interface{}) interface{} {
+	return multiply(x, y, true)
+}
+
+func multiply(x, y interface{}, promote bool) interface{} {
+	x, y, ops := coerce(x, y)
+	switch ops {
+	case intOps:
+		a, b := x.(int), y.(int)
+		ret := a * b
+		if a != 0 && (ret/a != b || (a == -1 && b == math.MinInt)) {
+			if !promote {
+				panic(integerOverflow)
+			}
+			return new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(b)))
+		}
+		return ret
+	case bigIntOps:
+		return new(big.Int).Mul(x.(*big.Int), y.(*big.Int))
+	case ratioOps:
+		return normalizeRat(new(big.Rat).Mul(x.(*big.Rat), y.(*big.Rat)))
+	case decimalOps:
+		return multiplyDecimals(x.(*BigDecimal), y.(*BigDecimal))
+	}
+	return x.(float64) * y.(float64)
+}
+
+// Gives x / y. Dividing integers gives a ratio if the result is not an integer.
+// Dividing decimals panics if the result can't be represented exactly as a
+// decimal. Dividing by zero panics, except for floats.
+func Divide(x, y interface{}) interface{} {
+	x, y, ops := coerce(x, y)
+	switch ops {
+	case intOps:
+		a, b := x.(int), y.(int)
+		if b == 0 {
+			panic(divideByZero)
+		}
+		if a%b == 0 && !(a == math.MinInt && b == -1) {
+			return a / b
+		}
+		return normalizeRat(new(big.Rat).SetFrac(big.NewInt(int64(a)), big.NewInt(int64(b))))
+	case bigIntOps:
+		if y.(*big.Int).Sign() == 0 {
+			panic(divideByZero)
+		}
+		return normalizeRat(new(big.Rat).SetFrac(x.(*big.Int), y.(*big.Int)))
+	case ratioOps:
+		if y.(*big.Rat).Sign() == 0 {
+			panic(divideByZero)
+		}
+		return normalizeRat(new(big.Rat).Quo(x.(*big.Rat), y.(*big.Rat)))
+	case decimalOps:
+		a, b := x.(*BigDecimal), y.(*BigDecimal)
+		if b.unscaled.Sign() == 0 {
+			panic(divideByZero)
+		}
+		return ratToDecimal(new(big.Rat).Quo(a.Rat(), b.Rat()), a.scale-b.scale)
+	}
+	return x.(float64) / y.(float64)
+}
+
+// Gives -1, 0 or 1 depending on whether x is less than, equal to or greater than
+// y. If any of them is a float, they are compared as floats; otherwise, they are
+// compared exactly.
+func Compare(x, y interface{}) int {
+	x, y, ops := coerce(x, y)
+	switch ops {
+	case intOps:
+		a, b := x.(int), y.(int)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+		return 0
+	case floatOps:
+		a, b := x.(float64), y.(float64)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+		return 0
+	}
+	return toRat(x).Cmp(toRat(y))
+}
+
+// Number categories for equivalence: numbers in different categories are never
+// equivalent, so that (= 1 1.0) is false but (= 1 1N) is true.
+const (
+	noCategory = iota
+	integerCategory
+	ratioCategory
+	decimalCategory
+	floatCategory
+)
+
+func numberCategory(ops numberOps) int {
+	switch ops {
+	case intOps, bigIntOps:
+		return integerCategory
+	case ratioOps:
+		return ratioCategory
+	case decimalOps:
+		return decimalCategory
+	case floatOps:
+		return floatCategory
+	}
+	return noCategory
+}
+
+// Tells whether a and b are equivalent numbers, if both are numbers.
+func numberEquiv(a, b interface{}) (bool, bool) {
+	a, aops := number(a)
+	b, bops := number(b)
+	if aops == notANumber || bops == notANumber {
+		return false, false
+	}
+	if numberCategory(aops) != numberCategory(bops) {
+		return false, true
+	}
+	if aops == floatOps {
+		return toFloat(a) == toFloat(b), true
+	}
+	return Compare(a, b) == 0, true
+}
+
+func numberHash(x interface{}) (uint32, bool) {
+	x, ops := number(x)
+	switch ops {
+	case intOps:
+		return hashUint64(uint64(x.(int))), true
+	case bigIntOps:
+		return hashBigInt(x.(*big.Int)), true
+	case ratioOps:
+		r := x.(*big.Rat)
+		return hashBigInt(r.Num()) ^ hashBigInt(r.Denom()), true
+	case decimalOps:
+		d := x.(*BigDecimal).stripZeros()
+		return 31*hashBigInt(d.unscaled) + uint32(d.scale), true
+	case floatOps:
+		f := x.(float64)
+		if f == 0 {
+			f = 0 // Normalize -0.0.
+		}
+		return hashUint64(math.Float64bits(f)) ^ 0x7ff, true
+	}
+	return 0, false
+}
+
+// Hashes a *big.Int as the int with its value, if it fits in one.
+func hashBigInt(n *big.Int) uint32 {
+	if n.IsInt64() {
+		return hashUint64(uint64(n.Int64()))
+	}
+	return hashString(n.String())
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^([-+]?)(?:(0)|([1-9][0-9]*)|0[xX]([0-9A-Fa-f]+)|0([0-7]+)|([1-9][0-9]?)[rR]([0-9A-Za-z]+)|0[0-9]+)(N)?$`)
+	ratioPattern = regexp.MustCompile(`^([-+]?[0-9]+)/([0-9]+)$`)
+	floatPattern = regexp.MustCompile(`^([-+]?[0-9]+(\.[0-9]*)?([eE][-+]?[0-9]+)?)(M)?$`)
+)
+
+// Parses a number literal as written in Gojure source code:
+//
+//	42 -42 +42          ints, or *big.Int if they don't fit in one
+//	42N                 *big.Int
+//	0x2A 052 2r101010   hexadecimal, octal and radix (2 to 36) integers
+//	1/3                 *big.Rat, or an integer if it is one
+//	4.2 42e-1 4.2e+0    float64
+//	4.2M 42e-1M         *BigDecimal
+//	##Inf ##-Inf ##NaN  float64 infinities and NaN
+//
+// Floating-point numbers out of float64's range are taken as infinities.
+func ParseNumber(s string) (interface{}, error) {
+	switch s {
+	case "##Inf":
+		return math.Inf(1), nil
+	case "##-Inf":
+		return math.Inf(-1), nil
+	case "##NaN":
+		return math.NaN(), nil
+	}
+	if m := intPattern.FindStringSubmatch(s); m != nil {
+		if m[2] != "" {
+			if m[8] != "" {
+				return big.NewInt(0), nil
+			}
+			return 0, nil
+		}
+		digits, base := m[3], 10
+		switch {
+		case m[4] != "":
+			digits, base = m[4], 16
+		case m[5] != "":
+			digits, base = m[5], 8
+		case m[7] != "":
+			digits = m[7]
+			base, _ = strconv.Atoi(m[6])
+			if base < 2 || base > 36 {
+				return nil, errors.New("invalid number: " + s)
+			}
+		}
+		if digits == "" {
+			return nil, errors.New("invalid number: " + s)
+		}
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return nil, errors.New("invalid number: " + s)
+		}
+		if m[1] == "-" {
+			n.Neg(n)
+		}
+		if m[8] == "" && n.IsInt64() && n.Int64() >= math.MinInt && n.Int64() <= math.MaxInt {
+			return int(n.Int64()), nil
+		}
+		return n, nil
+	}
+	if m := floatPattern.FindStringSubmatch(s); m != nil {
+		if m[4] != "" {
+			return parseDecimal(m[1])
+		}
+		f, err := strconv.ParseFloat(m[1], 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return nil, errors.New("invalid number: " + s)
+		}
+		return f, nil
+	}
+	if m := ratioPattern.FindStringSubmatch(s); m != nil {
+		num, _ := new(big.Int).SetString(strings.TrimPrefix(m[1], "+"), 10)
+		den, _ := new(big.Int).SetString(m[2], 10)
+		if den.Sign() == 0 {
+			return nil, errors.New("invalid number: " + s + ": " + divideByZero.Error())
+		}
+		r := normalizeRat(new(big.Rat).SetFrac(num, den))
+		if n, ok := r.(*big.Int); ok && n.IsInt64() && n.Int64() >= math.MinInt && n.Int64() <= math.MaxInt {
+			return int(n.Int64()), nil
+		}
+		return r, nil
+	}
+	return nil, errors.New("invalid number: " + s)
+}
+
+// Like ParseNumber, but panics if s is not a valid number.
+func MustParseNumber(s string) interface{} {
+	n, err := ParseNumber(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func parseDecimal(s string) (*BigDecimal, error) {
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		exp, err = strconv.Atoi(strings.TrimPrefix(s[i+1:], "+"))
+		if err != nil {
+			return nil, errors.New("invalid number: " + s + "M")
+		}
+		s = s[:i]
+	}
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = len(s) - i - 1
+		s = s[:i] + s[i+1:]
+	}
+	unscaled, ok := new(big.Int).SetString(strings.TrimPrefix(s, "+"), 10)
+	if !ok {
+		return nil, errors.New("invalid number: " + s + "M")
+	}
+	return &BigDecimal{unscaled, scale - exp}, nil
+}
+
+// Gives the source code literal for a number, as understood by ParseNumber.
+func NumberLiteral(x interface{}) string {
+	x, ops := number(x)
+	switch ops {
+	case intOps:
+		return strconv.Itoa(x.(int))
+	case bigIntOps:
+		return x.(*big.Int).String() + "N"
+	case ratioOps:
+		return x.(*big.Rat).String()
+	case decimalOps:
+		return x.(*BigDecimal).String() + "M"
+	case floatOps:
+		f := x.(float64)
+		switch {
+		case math.IsInf(f, 1):
+			return "##Inf"
+		case math.IsInf(f, -1):
+			return "##-Inf"
+		case math.IsNaN(f):
+			return "##NaN"
+		}
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	}
+	panic(fmt.Errorf("not a number: %v", x))
+}
@@ -0,0 +1,175 @@
+package lang
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestArithmetic(t *testing.T) {
+	bigMax := new(big.Int).Add(big.NewInt(math.MaxInt64), big.NewInt(1))
+	cases := []struct {
+		name     string
+		f        func() interface{}
+		expected string
+	}{
+		{"add", func() interface{} { return Add(1, 2) }, "3"},
+		{"add Go ints", func() interface{} { return Add(int8(1), uint16(2)) }, "3"},
+		{"add promoting", func() interface{} { return AddP(math.MaxInt, 1) }, "9223372036854775808N"},
+		{"add promoting without overflow", func() interface{} { return AddP(1, 2) }, "3"},
+		{"subtract promoting", func() interface{} { return SubtractP(math.MinInt, 1) }, "-9223372036854775809N"},
+		{"multiply promoting", func() interface{} { return MultiplyP(math.MaxInt, 2) }, "18446744073709551614N"},
+		{"multiply promoting -1", func() interface{} { return MultiplyP(-1, math.MinInt) }, "9223372036854775808N"},
+		{"big int stays big", func() interface{} { return Subtract(bigMax, bigMax) }, "0N"},
+		{"Go int out of int range", func() interface{} { return Add(uint64(math.MaxUint64), 0) }, "18446744073709551615N"},
+		{"divide exactly", func() interface{} { return Divide(6, 3) }, "2"},
+		{"divide to ratio", func() interface{} { return Divide(6, 4) }, "3/2"},
+		{"divide to negative ratio", func() interface{} { return Divide(1, -3) }, "-1/3"},
+		{"divide MinInt by -1", func() interface{} { return Divide(math.MinInt, -1) }, "9223372036854775808N"},
+		{"ratio to integer", func() interface{} { return Add(big.NewRat(1, 2), big.NewRat(1, 2)) }, "1N"},
+		{"ratio stays ratio", func() interface{} { return Multiply(big.NewRat(1, 2), big.NewRat(2, 3)) }, "1/3"},
+		{"ratio and int", func() interface{} { return Add(big.NewRat(1, 2), 1) }, "3/2"},
+		{"decimal and int", func() interface{} { return Add(1, MustParseNumber("1.5M")) }, "2.5M"},
+		{"decimal and big int", func() interface{} { return Multiply(bigMax, MustParseNumber("0.5M")) }, "4611686018427387904.0M"},
+		{"decimal and ratio", func() interface{} { return Add(big.NewRat(1, 4), MustParseNumber("1.5M")) }, "1.75M"},
+		{"decimal scales", func() interface{} { return Multiply(MustParseNumber("1.5M"), MustParseNumber("1.50M")) }, "2.250M"},
+		{"decimal divide", func() interface{} { return Divide(MustParseNumber("1M"), MustParseNumber("4M")) }, "0.25M"},
+		{"float and decimal", func() interface{} { return Add(MustParseNumber("1.5M"), 1.0) }, "2.5"},
+		{"float and ratio", func() interface{} { return Add(big.NewRat(1, 2), 1.0) }, "1.5"},
+		{"float and int", func() interface{} { return Multiply(2, 1.5) }, "3.0"},
+		{"float divide by zero", func() interface{} { return Divide(1.0, 0) }, "##Inf"},
+	}
+	for _, c := range cases {
+		if got := NumberLiteral(c.f()); got != c.expected {
+			t.Errorf("Case '%s' expected to give %s, gave %s.", c.name, c.expected, got)
+		}
+	}
+
+	panics := []struct {
+		name     string
+		f        func()
+		expected error
+	}{
+		{"add overflow", func() { Add(math.MaxInt, 1) }, integerOverflow},
+		{"subtract overflow", func() { Subtract(math.MinInt, 1) }, integerOverflow},
+		{"multiply overflow", func() { Multiply(math.MaxInt, 2) }, integerOverflow},
+		{"multiply -1 overflow", func() { Multiply(-1, math.MinInt) }, integerOverflow},
+		{"divide by zero", func() { Divide(1, 0) }, divideByZero},
+		{"divide big by zero", func() { Divide(bigMax, big.NewInt(0)) }, divideByZero},
+		{"divide ratio by zero", func() { Divide(big.NewRat(1, 2), 0) }, divideByZero},
+		{"divide decimal by zero", func() { Divide(MustParseNumber("1M"), 0) }, divideByZero},
+		{"non-terminating decimal", func() { Divide(MustParseNumber("1M"), MustParseNumber("3M")) }, nonTerminatingDecimal},
+	}
+	for _, c := range panics {
+		func() {
+			defer func() {
+				if r := recover(); r != c.expected {
+					t.Errorf("Case '%s' expected to panic with %v, got %v.", c.name, c.expected, r)
+				}
+			}()
+			c.f()
+		}()
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		x, y     interface{}
+		expected int
+	}{
+		{1, 2, -1},
+		{2, 1, 1},
+		{int64(2), uint8(2), 0},
+		{math.MaxInt, MustParseNumber("9223372036854775808"), -1},
+		{big.NewRat(1, 3), big.NewRat(1, 2), -1},
+		{big.NewRat(1, 2), 0.5, 0},
+		{big.NewRat(3, 2), MustParseNumber("1.5M"), 0},
+		{MustParseNumber("1.50M"), MustParseNumber("1.5M"), 0},
+		{MustParseNumber("-0.1M"), 0, -1},
+		{1, 1.5, -1},
+		{math.Inf(-1), math.MinInt, -1},
+	}
+	for _, c := range cases {
+		if got := Compare(c.x, c.y); got != c.expected {
+			t.Errorf("Compare(%v, %v) expected to give %d, gave %d.", c.x, c.y, c.expected, got)
+		}
+		if got := Compare(c.y, c.x); got != -c.expected {
+			t.Errorf("Compare(%v, %v) expected to give %d, gave %d.", c.y, c.x, -c.expected, got)
+		}
+	}
+}
+
+func TestNumberEquiv(t *testing.T) {
+	// Numbers in the same group are equivalent, and those in different groups
+	// are not.
+	groups := [][]interface{}{
+		{1, int64(1), uint8(1), big.NewInt(1), new(big.Rat).SetInt64(1)},
+		{1.0, float32(1)},
+		{MustParseNumber("1M"), MustParseNumber("1.0M"), MustParseNumber("1.00M")},
+		{big.NewRat(1, 2)},
+		{0.5},
+		{MustParseNumber("0.5M")},
+		{0.0, math.Copysign(0, -1)},
+		{0, big.NewInt(0)},
+		{MustParseNumber("9223372036854775808"), uint64(1 << 63)},
+		{math.Inf(1)},
+	}
+	for i, g := range groups {
+		for _, a := range g {
+			for j, h := range groups {
+				for _, b := range h {
+					eq, ok := numberEquiv(a, b)
+					if !ok || eq != (i == j) {
+						t.Errorf("numberEquiv(%v %T, %v %T) expected to give %v, gave %v %v.", a, a, b, b, i == j, eq, ok)
+					}
+					if i == j && Hash(a) != Hash(b) {
+						t.Errorf("%v %T and %v %T are equivalent, but hash to %d and %d.", a, a, b, b, Hash(a), Hash(b))
+					}
+				}
+			}
+		}
+	}
+	if _, ok := numberEquiv(1, "1"); ok {
+		t.Errorf("numberEquiv expected to tell \"1\" is not a number.")
+	}
+	if _, ok := numberEquiv(Char('1'), 1); ok {
+		t.Errorf("numberEquiv expected to tell a Char is not a number.")
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected string
+	}{
+		{"42", "42"},
+		{"-0x2A", "-42"},
+		{"42N", "42N"},
+		{"9223372036854775808", "9223372036854775808N"},
+		{"8/4", "2"},
+		{"-6/4", "-3/2"},
+		{"1.5", "1.5"},
+		{"15e-1M", "1.5M"},
+		{"1e400", "##Inf"},
+		{"-1e400", "##-Inf"},
+		{"1e-400", "0.0"},
+		{"##Inf", "##Inf"},
+		{"##-Inf", "##-Inf"},
+		{"##NaN", "##NaN"},
+	}
+	for _, c := range cases {
+		n, err := ParseNumber(c.source)
+		if err != nil {
+			t.Errorf("Case '%s': unexpected error: %v", c.source, err)
+			continue
+		}
+		if got := NumberLiteral(n); got != c.expected {
+			t.Errorf("Case '%s' expected to give %s, gave %s.", c.source, c.expected, got)
+		}
+	}
+	for _, s := range []string{"1/0", "37r1", "1e", "##inf", "0x"} {
+		if n, err := ParseNumber(s); err == nil {
+			t.Errorf("Case '%s' expected to fail, gave %v.", s, n)
+		}
+	}
+}
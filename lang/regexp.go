@@ -0,0 +1,115 @@
+package lang
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+
+	pers "github.com/tcard/gojure/persistent"
+)
+
+var noMatch = errors.New("no match found")
+
+// Gives a regexp from a pattern, which may be a string or a *regexp.Regexp
+// already. It panics if the pattern doesn't compile.
+func RePattern(pattern interface{}) *regexp.Regexp {
+	if re, ok := pattern.(*regexp.Regexp); ok {
+		return re
+	}
+	return regexp.MustCompile(pattern.(string))
+}
+
+// A Matcher goes through the successive matches of a regexp in a string, as
+// re-find does when given one, and remembers the last one for re-groups.
+type Matcher struct {
+	s       string
+	matches [][]int
+	last    []int
+}
+
+// Makes a new Matcher for the matches of re in s.
+func NewMatcher(re *regexp.Regexp, s string) *Matcher {
+	return &Matcher{s: s, matches: re.FindAllStringSubmatchIndex(s, -1)}
+}
+
+// Moves to the next match, telling whether there was one.
+func (m *Matcher) Find() bool {
+	if len(m.matches) == 0 {
+		m.last = nil
+		return false
+	}
+	m.last, m.matches = m.matches[0], m.matches[1:]
+	return true
+}
+
+// Gives the groups of the last match, as ReGroups does. It panics if there is
+// no last match.
+func (m *Matcher) Groups() interface{} {
+	if m.last == nil {
+		panic(noMatch)
+	}
+	return groups(m.s, m.last)
+}
+
+// Gives the groups of the last match of m. See ReFind for how groups are given.
+func ReGroups(m *Matcher) interface{} {
+	return m.Groups()
+}
+
+// Gives the first match of re in s, or nil if there isn't one. If re has no
+// groups, the match is the matched string; else, it is a vector with the matched
+// string followed by each group, which is nil if it didn't take part in the
+// match.
+func ReFind(re *regexp.Regexp, s string) interface{} {
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil
+	}
+	return groups(s, loc)
+}
+
+// Anchored copies of the regexps given to ReMatches, by pattern, so that each is
+// compiled once.
+var anchoredRegexps sync.Map
+
+// Gives the match of re with the whole of s, as ReFind gives it, or nil if re
+// doesn't match the whole of s.
+func ReMatches(re *regexp.Regexp, s string) interface{} {
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil
+	}
+	// The first match may not be the whole of s even if some other is, as
+	// with a|ab and "ab"; only then is an anchored copy of re needed.
+	if loc[0] == 0 && loc[1] == len(s) {
+		return groups(s, loc)
+	}
+	anchored, ok := anchoredRegexps.Load(re.String())
+	if !ok {
+		anchored, _ = anchoredRegexps.LoadOrStore(re.String(), regexp.MustCompile(`\A(?:`+re.String()+`)\z`))
+	}
+	return ReFind(anchored.(*regexp.Regexp), s)
+}
+
+// Gives a list with each successive match of re in s, as ReFind gives them, or
+// nil if there are none.
+func ReSeq(re *regexp.Regexp, s string) *pers.List {
+	items := []interface{}{}
+	for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+		items = append(items, groups(s, loc))
+	}
+	return pers.NewList(items...)
+}
+
+func groups(s string, loc []int) interface{} {
+	if len(loc) == 2 {
+		return s[loc[0]:loc[1]]
+	}
+	items := make([]interface{}, len(loc)/2)
+	for i := range items {
+		if loc[2*i] >= 0 {
+			items[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return pers.NewVector(items...)
+}
@@ -0,0 +1,42 @@
+package lang
+
+import (
+	"regexp"
+	"testing"
+
+	pers "github.com/tcard/gojure/persistent"
+)
+
+func TestReMatches(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		s        string
+		expected interface{}
+	}{
+		{`abc`, "abc", "abc"},
+		{`abc`, "abcd", nil},
+		{`abc`, "xabc", nil},
+		{`b`, "abc", nil},
+		{`a*`, "", ""},
+		{`a|ab`, "ab", "ab"},
+		{`(a|ab)(c|bcd)`, "abcd", pers.NewVector("abcd", "a", "bcd")},
+		{`(a)|(b)`, "b", pers.NewVector("b", nil, "b")},
+		{`(?i)ABC`, "abc", "abc"},
+		{`^a$|b`, "a", "a"},
+	}
+	for _, c := range cases {
+		re := regexp.MustCompile(c.pattern)
+		// Twice, to go through the cached anchored regexp too.
+		for i := 0; i < 2; i++ {
+			if got := ReMatches(re, c.s); !Equiv(got, c.expected) {
+				t.Errorf("Case '%s' on '%s' expected to give %v, gave %v.", c.pattern, c.s, c.expected, got)
+			}
+		}
+	}
+	if _, ok := anchoredRegexps.Load(`(?i)ABC`); ok {
+		t.Errorf("Regexp expected not to be anchored when its first match is the whole string.")
+	}
+	if _, ok := anchoredRegexps.Load(`a|ab`); !ok {
+		t.Errorf("Regexp expected to be anchored when its first match is not the whole string.")
+	}
+}
@@ -1,6 +1,7 @@
 package lang
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/tcard/gojure/persistent"
@@ -32,7 +33,21 @@ func GetImport(imp interface{}) interface{} {
 	}
 }
 
+// Tells whether x is logically false: nil or false. Everything else is true.
 func IsFalse(x interface{}) bool {
 	v, ok := x.(bool)
-	return x == nil || !ok || !v
+	return x == nil || ok && !v
+}
+
+// An ArityException is what a function panics with when it's called with a
+// number of arguments it doesn't take.
+type ArityException struct {
+	// Actual is the number of arguments the function was called with.
+	Actual int
+	// Name is the name of the function.
+	Name string
+}
+
+func (e *ArityException) Error() string {
+	return fmt.Sprintf("wrong number of args (%d) passed to: %s", e.Actual, e.Name)
 }
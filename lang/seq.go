@@ -128,24 +128,15 @@ func (l *List) String() string {
 }
 
 type Vector struct {
-	*pers.Vector
-	from int
+	*pers.SubVector
 }
 
 func NewVector(items ...interface{}) Seq {
-	v := &Vector{pers.NewVector(items...), 0}
+	v := pers.NewVector(items...)
 	if v.Count() == 0 {
 		return nil
 	}
-	return v
-}
-
-func (v *Vector) Count() int {
-	return v.Vector.Count() - v.from
-}
-
-func (v *Vector) Nth(i int) interface{} {
-	return v.Vector.Nth(i + v.from)
+	return &Vector{v.Subvec(0, v.Count())}
 }
 
 func (v *Vector) First() interface{} {
@@ -156,17 +147,49 @@ func (v *Vector) Rest() Seq {
 	if v.Count() <= 1 {
 		return nil
 	}
-	return &Vector{v.Vector, v.from + 1}
+	return &Vector{v.Subvec(1, v.Count())}
 }
 
 func (v *Vector) Cons(x interface{}) Seq {
-	newV := &Vector{pers.NewVector(x), 0}
+	t := pers.NewVector(x).AsTransient()
 	for i := 0; i < v.Count(); i++ {
-		newV.Vector = newV.Vector.Conj(v.Nth(i))
+		t.Conj(v.Nth(i))
 	}
-	return newV
+	newV := t.Persistent()
+	return &Vector{newV.Subvec(0, newV.Count())}
 }
 
 func (v *Vector) String() string {
 	return Format(v, "[", "]")
 }
+
+// Gives the items in coll, in order: the elements of lists, vectors, queues and
+// seqs, the items of sets, the entries of maps as [key value] vectors and the
+// characters of strings. nil has no items. It panics if coll is not a
+// collection.
+func Items(coll interface{}) []interface{} {
+	ret := []interface{}{}
+	if coll == nil {
+		return ret
+	}
+	if next, ok := sequential(coll); ok {
+		for x, more := next(); more; x, more = next() {
+			ret = append(ret, x)
+		}
+	} else if next, ok := mapEntries(coll); ok {
+		for k, v, more := next(); more; k, v, more = next() {
+			ret = append(ret, pers.NewVector(k, v))
+		}
+	} else if next, ok := setItems(coll); ok {
+		for x, more := next(); more; x, more = next() {
+			ret = append(ret, x)
+		}
+	} else if s, ok := coll.(string); ok {
+		for _, c := range s {
+			ret = append(ret, Char(c))
+		}
+	} else {
+		panic(fmt.Errorf("don't know how to get items from %v", coll))
+	}
+	return ret
+}
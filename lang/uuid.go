@@ -0,0 +1,40 @@
+package lang
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+// A UUID is a universally unique identifier, as read from literals like
+// #uuid "f81d4fae-7dec-11d0-a765-00a0c91e6bf6".
+type UUID [16]byte
+
+var badUUID = errors.New("UUID must be of the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+
+// Parses a UUID in its canonical form, as in
+// "f81d4fae-7dec-11d0-a765-00a0c91e6bf6". Hex digits may be upper or lower case.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, badUUID
+	}
+	digits := s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return u, badUUID
+	}
+	return u, nil
+}
+
+// Like ParseUUID, but panics if s is not a valid UUID.
+func MustParseUUID(s string) UUID {
+	u, err := ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,150 @@
+package persistent
+
+import (
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// A Hasher gives its own hash code. Values that are equivalent must have the same
+// hash code.
+type Hasher interface {
+	Hash() uint32
+}
+
+// An Equiver decides by itself whether it is equivalent to other value.
+type Equiver interface {
+	Equiv(other interface{}) bool
+}
+
+// An Equality tells how a HashMap hashes and compares its keys, or a HashSet its
+// items. Values that are equivalent must have the same hash code.
+//
+// Maps and sets made without one use Go's equality, which only knows about Go
+// values: values implementing Hasher give their own hash code, and those
+// implementing Equiver decide whether they are equivalent to others by
+// themselves; otherwise, Go's == is used, or reflect.DeepEqual for values that
+// are not comparable. Package github.com/tcard/gojure/lang has an Equality with
+// Gojure's semantics.
+type Equality interface {
+	Hash(x interface{}) uint32
+	Equiv(a, b interface{}) bool
+}
+
+type goEquality struct{}
+
+func (goEquality) Hash(x interface{}) uint32 {
+	if x == nil {
+		return 0
+	}
+	if h, ok := x.(Hasher); ok {
+		return h.Hash()
+	}
+	v := reflect.ValueOf(x)
+	return hashValue(v, !v.Type().Comparable(), maxHashDepth)
+}
+
+// How many pointers, slices, arrays and maps deep hashValue looks into a value,
+// which keeps it from going around cyclic ones forever.
+const maxHashDepth = 8
+
+// Hashes v consistently with how goEquality compares it: by == if deep is false,
+// which compares pointers by address, or by reflect.DeepEqual otherwise, which
+// follows them. Whatever is further than depth levels deep is left out.
+func hashValue(v reflect.Value, deep bool, depth int) uint32 {
+	switch v.Kind() {
+	case reflect.String:
+		return hashString(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return hashUint64(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return hashUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return 31*hashFloat(real(c)) + hashFloat(imag(c))
+	case reflect.Bool:
+		if v.Bool() {
+			return 1231
+		}
+		return 1237
+	case reflect.Chan, reflect.UnsafePointer:
+		return hashUint64(uint64(v.Pointer()))
+	case reflect.Ptr:
+		if !deep {
+			return hashUint64(uint64(v.Pointer()))
+		}
+		if v.IsNil() || depth == 0 {
+			return 0
+		}
+		return hashValue(v.Elem(), deep, depth-1)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return hashValue(v.Elem(), deep, depth)
+	case reflect.Struct:
+		h := uint32(1)
+		for i := 0; i < v.NumField(); i++ {
+			h = 31*h + hashValue(v.Field(i), deep, depth)
+		}
+		return h
+	case reflect.Array, reflect.Slice:
+		h := uint32(1)
+		if depth == 0 {
+			return h
+		}
+		for i := 0; i < v.Len(); i++ {
+			h = 31*h + hashValue(v.Index(i), deep, depth-1)
+		}
+		return h
+	case reflect.Map:
+		// Entries are in no particular order, so their hashes are added up.
+		h := uint32(0)
+		if depth == 0 {
+			return h
+		}
+		for it := v.MapRange(); it.Next(); {
+			h += hashValue(it.Key(), deep, depth-1) ^ hashValue(it.Value(), deep, depth-1)
+		}
+		return h
+	}
+	// Functions are only ever equal when they're both nil.
+	return 0
+}
+
+func (goEquality) Equiv(a, b interface{}) bool {
+	if e, ok := a.(Equiver); ok {
+		return e.Equiv(b)
+	}
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if !ta.Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+func hashString(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// Hashes f so that 0.0 and -0.0, which are ==, have the same hash code.
+func hashFloat(f float64) uint32 {
+	if f == 0 {
+		f = 0
+	}
+	return hashUint64(math.Float64bits(f))
+}
+
+func hashUint64(n uint64) uint32 {
+	return uint32(n) ^ uint32(n>>32)
+}
@@ -0,0 +1,507 @@
+package persistent
+
+// This implementation is practically copied from Clojure's
+// clojure.lang.PersistentHashMap.
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// A persistent HashMap is an associative data structure, mapping keys to values,
+// that implements almost-constant-time lookup, association and dissociation.
+// It is implemented as a hash array mapped trie.
+// Keys are hashed and compared according to an Equality; maps made by
+// NewHashMap, and the zero value, use Go's.
+// A HashMap value is immutable; every operation on it produces a new, independent
+// value from it. The zero value is an empty map.
+type HashMap struct {
+	eq     Equality
+	count  int
+	root   hashMapNode
+	hasNil bool
+	nilVal interface{}
+	meta   *HashMap
+}
+
+var (
+	oddKeyValues = errors.New("odd number of keys and values")
+)
+
+// Makes a new map from keys and values interleaved, as in k1, v1, k2, v2... It will
+// panic if the number of arguments is odd. Later values replace earlier ones
+// with an equivalent key.
+func NewHashMap(keyvals ...interface{}) *HashMap {
+	return NewHashMapWith(nil, keyvals...)
+}
+
+// Makes a new map like NewHashMap, whose keys are hashed and compared according
+// to eq. If eq is nil, Go's equality is used, as described in Equality.
+func NewHashMapWith(eq Equality, keyvals ...interface{}) *HashMap {
+	if len(keyvals)%2 != 0 {
+		panic(oddKeyValues)
+	}
+	ret := &HashMap{eq: eq}
+	for i := 0; i < len(keyvals); i += 2 {
+		ret = ret.Assoc(keyvals[i], keyvals[i+1])
+	}
+	return ret
+}
+
+// Gives the Equality by which the map hashes and compares its keys.
+func (m *HashMap) Equality() Equality {
+	if m.eq == nil {
+		return goEquality{}
+	}
+	return m.eq
+}
+
+// Gives the number of entries in the map.
+func (m *HashMap) Count() int {
+	return m.count
+}
+
+// Gives the value associated with key, and whether it was found at all.
+func (m *HashMap) Get(key interface{}) (interface{}, bool) {
+	if key == nil {
+		return m.nilVal, m.hasNil
+	}
+	if m.root == nil {
+		return nil, false
+	}
+	eq := m.Equality()
+	return m.root.find(eq, 0, eq.Hash(key), key)
+}
+
+// Tells whether the map holds an entry for key.
+func (m *HashMap) Contains(key interface{}) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Makes a new map in which key is associated with val.
+func (m *HashMap) Assoc(key interface{}, val interface{}) *HashMap {
+	if key == nil {
+		count := m.count
+		if !m.hasNil {
+			count++
+		}
+		return &HashMap{m.eq, count, m.root, true, val, m.meta}
+	}
+	addedLeaf := false
+	root := m.root
+	if root == nil {
+		root = emptyBitmapIndexedNode
+	}
+	eq := m.Equality()
+	newRoot := root.assoc(eq, 0, eq.Hash(key), key, val, &addedLeaf)
+	count := m.count
+	if addedLeaf {
+		count++
+	}
+	return &HashMap{m.eq, count, newRoot, m.hasNil, m.nilVal, m.meta}
+}
+
+// Makes a new map without any entry for key.
+func (m *HashMap) Dissoc(key interface{}) *HashMap {
+	if key == nil {
+		if !m.hasNil {
+			return m
+		}
+		return &HashMap{m.eq, m.count - 1, m.root, false, nil, m.meta}
+	}
+	if m.root == nil {
+		return m
+	}
+	eq := m.Equality()
+	newRoot := m.root.without(eq, 0, eq.Hash(key), key)
+	if newRoot == m.root {
+		return m
+	}
+	return &HashMap{m.eq, m.count - 1, newRoot, m.hasNil, m.nilVal, m.meta}
+}
+
+// Gives an iterator over the entries in the map, in no particular order.
+func (m *HashMap) Iterator() *HashMapIterator {
+	it := &HashMapIterator{pendingNil: m.hasNil, nilVal: m.nilVal}
+	if m.root != nil {
+		it.stack = []hashMapIterFrame{{node: m.root}}
+	}
+	return it
+}
+
+// Gives the metadata attached to the map, or nil if it has none.
+func (m *HashMap) Meta() *HashMap {
+	return m.meta
+}
+
+// Makes a new map with the same entries as m and meta as its metadata.
+func (m *HashMap) WithMeta(meta *HashMap) *HashMap {
+	return &HashMap{m.eq, m.count, m.root, m.hasNil, m.nilVal, meta}
+}
+
+func (m *HashMap) String() string {
+	s := "{"
+	first := true
+	for it := m.Iterator(); it.Next(); {
+		if !first {
+			s += ", "
+		}
+		first = false
+		s += fmt.Sprint(it.Key()) + " " + fmt.Sprint(it.Val())
+	}
+	s += "}"
+	return s
+}
+
+// A HashMapIterator walks the entries of a HashMap. Next must be called before
+// each entry is accessed, as in:
+//
+//	for it := m.Iterator(); it.Next(); {
+//		k, v := it.Key(), it.Val()
+//		...
+//	}
+type HashMapIterator struct {
+	stack      []hashMapIterFrame
+	pendingNil bool
+	nilVal     interface{}
+	key, val   interface{}
+}
+
+type hashMapIterFrame struct {
+	node hashMapNode
+	i    int
+}
+
+// Advances to the next entry, telling whether there is one.
+func (it *HashMapIterator) Next() bool {
+	if it.pendingNil {
+		it.pendingNil = false
+		it.key, it.val = nil, it.nilVal
+		return true
+	}
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		switch n := top.node.(type) {
+		case *bitmapIndexedNode:
+			if top.i < len(n.array) {
+				k, v := n.array[top.i], n.array[top.i+1]
+				top.i += 2
+				if k == nil {
+					it.stack = append(it.stack, hashMapIterFrame{node: v.(hashMapNode)})
+					continue
+				}
+				it.key, it.val = k, v
+				return true
+			}
+		case *hashCollisionNode:
+			if top.i < len(n.array) {
+				it.key, it.val = n.array[top.i], n.array[top.i+1]
+				top.i += 2
+				return true
+			}
+		case *arrayNode:
+			for top.i < len(n.array) && n.array[top.i] == nil {
+				top.i++
+			}
+			if top.i < len(n.array) {
+				child := n.array[top.i]
+				top.i++
+				it.stack = append(it.stack, hashMapIterFrame{node: child})
+				continue
+			}
+		}
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+	it.key, it.val = nil, nil
+	return false
+}
+
+// Gives the key of the current entry.
+func (it *HashMapIterator) Key() interface{} {
+	return it.key
+}
+
+// Gives the value of the current entry.
+func (it *HashMapIterator) Val() interface{} {
+	return it.val
+}
+
+// HashMaps are implemented as tries indexed by chunks of hashMapNodeShift bits of
+// the keys' hashes. Each node is either a bitmapIndexedNode, which holds up to 16
+// entries or subnodes in a compact array, an arrayNode, which holds subnodes in a
+// full array, or a hashCollisionNode, which holds entries whose keys have the
+// same hash.
+type hashMapNode interface {
+	assoc(eq Equality, shift uint, hash uint32, key, val interface{}, addedLeaf *bool) hashMapNode
+	without(eq Equality, shift uint, hash uint32, key interface{}) hashMapNode
+	find(eq Equality, shift uint, hash uint32, key interface{}) (interface{}, bool)
+}
+
+const (
+	hashMapNodeShift = 5
+	hashMapNodeLen   = 1 << hashMapNodeShift
+)
+
+func hashMask(hash uint32, shift uint) uint32 {
+	return (hash >> shift) & (hashMapNodeLen - 1)
+}
+
+func bitpos(hash uint32, shift uint) uint32 {
+	return 1 << hashMask(hash, shift)
+}
+
+// A bitmapIndexedNode's array holds pairs of items. If the first item of a pair
+// is nil, the second is a subnode; otherwise, they are a key and its value.
+type bitmapIndexedNode struct {
+	bitmap uint32
+	array  []interface{}
+}
+
+var emptyBitmapIndexedNode = &bitmapIndexedNode{}
+
+func (n *bitmapIndexedNode) index(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+func (n *bitmapIndexedNode) assoc(eq Equality, shift uint, hash uint32, key, val interface{}, addedLeaf *bool) hashMapNode {
+	bit := bitpos(hash, shift)
+	idx := n.index(bit)
+	if n.bitmap&bit != 0 {
+		keyOrNil := n.array[2*idx]
+		valOrNode := n.array[2*idx+1]
+		if keyOrNil == nil {
+			sub := valOrNode.(hashMapNode)
+			newSub := sub.assoc(eq, shift+hashMapNodeShift, hash, key, val, addedLeaf)
+			if newSub == sub {
+				return n
+			}
+			return &bitmapIndexedNode{n.bitmap, cloneAndSet(n.array, 2*idx+1, newSub)}
+		}
+		if eq.Equiv(key, keyOrNil) {
+			return &bitmapIndexedNode{n.bitmap, cloneAndSet(n.array, 2*idx+1, val)}
+		}
+		*addedLeaf = true
+		newArray := cloneAndSet(n.array, 2*idx, nil)
+		newArray[2*idx+1] = createHashMapNode(eq, shift+hashMapNodeShift, keyOrNil, valOrNode, hash, key, val)
+		return &bitmapIndexedNode{n.bitmap, newArray}
+	}
+	count := bits.OnesCount32(n.bitmap)
+	if count >= hashMapNodeLen/2 {
+		var nodes [hashMapNodeLen]hashMapNode
+		jdx := hashMask(hash, shift)
+		nodes[jdx] = emptyBitmapIndexedNode.assoc(eq, shift+hashMapNodeShift, hash, key, val, addedLeaf)
+		j := 0
+		for i := uint(0); i < hashMapNodeLen; i++ {
+			if (n.bitmap>>i)&1 != 0 {
+				if n.array[j] == nil {
+					nodes[i] = n.array[j+1].(hashMapNode)
+				} else {
+					nodes[i] = emptyBitmapIndexedNode.assoc(eq, shift+hashMapNodeShift,
+						eq.Hash(n.array[j]), n.array[j], n.array[j+1], addedLeaf)
+				}
+				j += 2
+			}
+		}
+		return &arrayNode{count + 1, nodes}
+	}
+	newArray := make([]interface{}, 2*(count+1))
+	copy(newArray, n.array[:2*idx])
+	newArray[2*idx] = key
+	newArray[2*idx+1] = val
+	copy(newArray[2*(idx+1):], n.array[2*idx:])
+	*addedLeaf = true
+	return &bitmapIndexedNode{n.bitmap | bit, newArray}
+}
+
+func (n *bitmapIndexedNode) without(eq Equality, shift uint, hash uint32, key interface{}) hashMapNode {
+	bit := bitpos(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n
+	}
+	idx := n.index(bit)
+	keyOrNil := n.array[2*idx]
+	valOrNode := n.array[2*idx+1]
+	if keyOrNil == nil {
+		sub := valOrNode.(hashMapNode)
+		newSub := sub.without(eq, shift+hashMapNodeShift, hash, key)
+		if newSub == sub {
+			return n
+		}
+		if newSub != nil {
+			return &bitmapIndexedNode{n.bitmap, cloneAndSet(n.array, 2*idx+1, newSub)}
+		}
+		if n.bitmap == bit {
+			return nil
+		}
+		return &bitmapIndexedNode{n.bitmap ^ bit, removePair(n.array, idx)}
+	}
+	if eq.Equiv(key, keyOrNil) {
+		if n.bitmap == bit {
+			return nil
+		}
+		return &bitmapIndexedNode{n.bitmap ^ bit, removePair(n.array, idx)}
+	}
+	return n
+}
+
+func (n *bitmapIndexedNode) find(eq Equality, shift uint, hash uint32, key interface{}) (interface{}, bool) {
+	bit := bitpos(hash, shift)
+	if n.bitmap&bit == 0 {
+		return nil, false
+	}
+	idx := n.index(bit)
+	keyOrNil := n.array[2*idx]
+	valOrNode := n.array[2*idx+1]
+	if keyOrNil == nil {
+		return valOrNode.(hashMapNode).find(eq, shift+hashMapNodeShift, hash, key)
+	}
+	if eq.Equiv(key, keyOrNil) {
+		return valOrNode, true
+	}
+	return nil, false
+}
+
+// An arrayNode holds a subnode, or nil, for every possible chunk of hash.
+type arrayNode struct {
+	count int
+	array [hashMapNodeLen]hashMapNode
+}
+
+func (n *arrayNode) assoc(eq Equality, shift uint, hash uint32, key, val interface{}, addedLeaf *bool) hashMapNode {
+	idx := hashMask(hash, shift)
+	sub := n.array[idx]
+	if sub == nil {
+		ret := &arrayNode{n.count + 1, n.array}
+		ret.array[idx] = emptyBitmapIndexedNode.assoc(eq, shift+hashMapNodeShift, hash, key, val, addedLeaf)
+		return ret
+	}
+	newSub := sub.assoc(eq, shift+hashMapNodeShift, hash, key, val, addedLeaf)
+	if newSub == sub {
+		return n
+	}
+	ret := &arrayNode{n.count, n.array}
+	ret.array[idx] = newSub
+	return ret
+}
+
+func (n *arrayNode) without(eq Equality, shift uint, hash uint32, key interface{}) hashMapNode {
+	idx := hashMask(hash, shift)
+	sub := n.array[idx]
+	if sub == nil {
+		return n
+	}
+	newSub := sub.without(eq, shift+hashMapNodeShift, hash, key)
+	if newSub == sub {
+		return n
+	}
+	if newSub == nil {
+		if n.count <= hashMapNodeLen/4 {
+			return n.pack(idx)
+		}
+		ret := &arrayNode{n.count - 1, n.array}
+		ret.array[idx] = nil
+		return ret
+	}
+	ret := &arrayNode{n.count, n.array}
+	ret.array[idx] = newSub
+	return ret
+}
+
+func (n *arrayNode) find(eq Equality, shift uint, hash uint32, key interface{}) (interface{}, bool) {
+	sub := n.array[hashMask(hash, shift)]
+	if sub == nil {
+		return nil, false
+	}
+	return sub.find(eq, shift+hashMapNodeShift, hash, key)
+}
+
+// Packs the subnodes of the arrayNode but the one at idx into a bitmapIndexedNode.
+func (n *arrayNode) pack(idx uint32) hashMapNode {
+	newArray := make([]interface{}, 0, 2*(n.count-1))
+	bitmap := uint32(0)
+	for i, sub := range n.array {
+		if uint32(i) != idx && sub != nil {
+			newArray = append(newArray, nil, sub)
+			bitmap |= 1 << uint(i)
+		}
+	}
+	return &bitmapIndexedNode{bitmap, newArray}
+}
+
+// A hashCollisionNode holds key and value pairs whose keys have the same hash.
+type hashCollisionNode struct {
+	hash  uint32
+	array []interface{}
+}
+
+func (n *hashCollisionNode) findIndex(eq Equality, key interface{}) int {
+	for i := 0; i < len(n.array); i += 2 {
+		if eq.Equiv(key, n.array[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (n *hashCollisionNode) assoc(eq Equality, shift uint, hash uint32, key, val interface{}, addedLeaf *bool) hashMapNode {
+	if hash == n.hash {
+		if idx := n.findIndex(eq, key); idx != -1 {
+			return &hashCollisionNode{n.hash, cloneAndSet(n.array, idx+1, val)}
+		}
+		newArray := make([]interface{}, len(n.array)+2)
+		copy(newArray, n.array)
+		newArray[len(n.array)] = key
+		newArray[len(n.array)+1] = val
+		*addedLeaf = true
+		return &hashCollisionNode{n.hash, newArray}
+	}
+	// Nest it in a bitmapIndexedNode.
+	nest := &bitmapIndexedNode{bitpos(n.hash, shift), []interface{}{nil, n}}
+	return nest.assoc(eq, shift, hash, key, val, addedLeaf)
+}
+
+func (n *hashCollisionNode) without(eq Equality, shift uint, hash uint32, key interface{}) hashMapNode {
+	idx := n.findIndex(eq, key)
+	if idx == -1 {
+		return n
+	}
+	if len(n.array) == 2 {
+		return nil
+	}
+	return &hashCollisionNode{n.hash, removePair(n.array, idx/2)}
+}
+
+func (n *hashCollisionNode) find(eq Equality, shift uint, hash uint32, key interface{}) (interface{}, bool) {
+	idx := n.findIndex(eq, key)
+	if idx == -1 {
+		return nil, false
+	}
+	return n.array[idx+1], true
+}
+
+func createHashMapNode(eq Equality, shift uint, key1, val1 interface{}, key2hash uint32, key2, val2 interface{}) hashMapNode {
+	key1hash := eq.Hash(key1)
+	if key1hash == key2hash {
+		return &hashCollisionNode{key1hash, []interface{}{key1, val1, key2, val2}}
+	}
+	addedLeaf := false
+	return emptyBitmapIndexedNode.
+		assoc(eq, shift, key1hash, key1, val1, &addedLeaf).
+		assoc(eq, shift, key2hash, key2, val2, &addedLeaf)
+}
+
+func cloneAndSet(array []interface{}, i int, x interface{}) []interface{} {
+	ret := make([]interface{}, len(array))
+	copy(ret, array)
+	ret[i] = x
+	return ret
+}
+
+func removePair(array []interface{}, i int) []interface{} {
+	ret := make([]interface{}, len(array)-2)
+	copy(ret, array[:2*i])
+	copy(ret[2*i:], array[2*(i+1):])
+	return ret
+}
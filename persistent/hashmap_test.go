@@ -0,0 +1,205 @@
+package persistent
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// A collider is a key with a hash code of choice, to make keys collide.
+type collider struct {
+	name string
+	hash uint32
+}
+
+func (c collider) Hash() uint32 {
+	return c.hash
+}
+
+type hashMapOp struct {
+	assoc bool
+	key   interface{}
+	val   interface{}
+}
+
+func TestHashMap(t *testing.T) {
+	a1, a2, a3 := collider{"a1", 42}, collider{"a2", 42}, collider{"a3", 42}
+	// Same lowest 5 bits as a1, so they share a subnode at the first level.
+	b := collider{"b", 42 | 1<<5}
+	cases := []struct {
+		name     string
+		ops      []hashMapOp
+		expected map[interface{}]interface{}
+	}{
+		{"empty", nil, map[interface{}]interface{}{}},
+		{"assoc", []hashMapOp{{true, 1, "a"}, {true, "b", 2}}, map[interface{}]interface{}{1: "a", "b": 2}},
+		{"replace", []hashMapOp{{true, 1, "a"}, {true, 1, "b"}}, map[interface{}]interface{}{1: "b"}},
+		{"dissoc", []hashMapOp{{true, 1, "a"}, {true, 2, "b"}, {false, 1, nil}}, map[interface{}]interface{}{2: "b"}},
+		{"dissoc absent", []hashMapOp{{true, 1, "a"}, {false, 2, nil}}, map[interface{}]interface{}{1: "a"}},
+		{"nil key", []hashMapOp{{true, nil, 1}, {true, 2, nil}}, map[interface{}]interface{}{nil: 1, 2: nil}},
+		{"nil key replaced", []hashMapOp{{true, nil, 1}, {true, nil, 2}}, map[interface{}]interface{}{nil: 2}},
+		{"nil key dissoc", []hashMapOp{{true, nil, 1}, {true, 1, 1}, {false, nil, nil}, {false, nil, nil}}, map[interface{}]interface{}{1: 1}},
+		{"collision", []hashMapOp{{true, a1, 1}, {true, a2, 2}, {true, a3, 3}}, map[interface{}]interface{}{a1: 1, a2: 2, a3: 3}},
+		{"collision replace", []hashMapOp{{true, a1, 1}, {true, a2, 2}, {true, a2, 3}}, map[interface{}]interface{}{a1: 1, a2: 3}},
+		{"collision dissoc", []hashMapOp{{true, a1, 1}, {true, a2, 2}, {true, a3, 3}, {false, a2, nil}}, map[interface{}]interface{}{a1: 1, a3: 3}},
+		{"collision nested", []hashMapOp{{true, a1, 1}, {true, a2, 2}, {true, b, 3}}, map[interface{}]interface{}{a1: 1, a2: 2, b: 3}},
+		{"collision nested dissoc", []hashMapOp{{true, a1, 1}, {true, a2, 2}, {true, b, 3}, {false, a1, nil}, {false, b, nil}}, map[interface{}]interface{}{a2: 2}},
+	}
+	for _, c := range cases {
+		m := NewHashMap()
+		for _, op := range c.ops {
+			if op.assoc {
+				m = m.Assoc(op.key, op.val)
+			} else {
+				m = m.Dissoc(op.key)
+			}
+		}
+		if err := checkHashMap(m, c.expected); err != nil {
+			t.Errorf("Case '%s': %s", c.name, err)
+		}
+	}
+}
+
+func TestHashMapPersistence(t *testing.T) {
+	// Keys share hash codes in groups of three, and those share their lowest
+	// bits with others, so there are collision nodes at several levels.
+	key := func(i int) collider {
+		return collider{fmt.Sprint(i), uint32(i/3) * (1 + 1<<5 + 1<<10)}
+	}
+	m := NewHashMap()
+	expected := map[interface{}]interface{}{}
+	for i := 0; i < 300; i++ {
+		m = m.Assoc(key(i), i)
+		expected[key(i)] = i
+	}
+	if err := checkHashMap(m, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nodes that a change copies are shared with the map it was made from,
+	// which must be left as it was.
+	changed := m
+	changedExpected := map[interface{}]interface{}{}
+	for i := 0; i < 300; i += 2 {
+		changed = changed.Assoc(key(i), -i).Dissoc(key(i + 1))
+		changedExpected[key(i)] = -i
+	}
+	if err := checkHashMap(changed, changedExpected); err != nil {
+		t.Errorf("Changed map: %s", err)
+	}
+	if err := checkHashMap(m, expected); err != nil {
+		t.Errorf("Original map after changes: %s", err)
+	}
+
+	for i := 0; i < 300; i++ {
+		m = m.Dissoc(key(i))
+	}
+	if m.Count() != 0 || m.root != nil {
+		t.Errorf("Map expected to be empty after removing every key, is %v with root %#v.", m, m.root)
+	}
+}
+
+func TestHashMapCollapse(t *testing.T) {
+	m := NewHashMap()
+	for i := 0; i < hashMapNodeLen; i++ {
+		m = m.Assoc(collider{"", uint32(i)}, i)
+	}
+	if _, ok := m.root.(*arrayNode); !ok {
+		t.Fatalf("Root of a map with %d entries expected to be an arrayNode, is %T.", hashMapNodeLen, m.root)
+	}
+	for i := 0; i < hashMapNodeLen-hashMapNodeLen/4+1; i++ {
+		m = m.Dissoc(collider{"", uint32(i)})
+	}
+	if _, ok := m.root.(*bitmapIndexedNode); !ok {
+		t.Errorf("Root expected to be packed into a bitmapIndexedNode, is %T.", m.root)
+	}
+	expected := map[interface{}]interface{}{}
+	for i := hashMapNodeLen - hashMapNodeLen/4 + 1; i < hashMapNodeLen; i++ {
+		expected[collider{"", uint32(i)}] = i
+	}
+	if err := checkHashMap(m, expected); err != nil {
+		t.Error(err)
+	}
+
+	a1, a2 := collider{"a1", 7}, collider{"a2", 7}
+	m = NewHashMap(a1, 1, a2, 2).Dissoc(a1)
+	if err := checkHashMap(m, map[interface{}]interface{}{a2: 2}); err != nil {
+		t.Error(err)
+	}
+	if m = m.Dissoc(a2); m.root != nil {
+		t.Errorf("Root expected to be nil after removing the last colliding key, is %#v.", m.root)
+	}
+}
+
+// A node is a key that isn't comparable, so Go's equality compares it with
+// reflect.DeepEqual, following its pointers.
+type node struct {
+	val  float64
+	next *node
+	tags []string
+}
+
+func TestHashMapGoEquality(t *testing.T) {
+	one, otherOne := 1, 1
+	cyclic := &node{val: 1}
+	cyclic.next = cyclic
+	otherCyclic := &node{val: 1}
+	otherCyclic.next = otherCyclic
+	cases := []struct {
+		name     string
+		key      interface{}
+		equal    interface{}
+		notEqual interface{}
+	}{
+		{"zeros", 0.0, math.Copysign(0, -1), 1.0},
+		{"pointers", &one, &one, &otherOne},
+		{"comparable structs with pointers", struct{ p *int }{&one}, struct{ p *int }{&one}, struct{ p *int }{&otherOne}},
+		{"slices", []int{1, 2}, []int{1, 2}, []int{2, 1}},
+		{"nodes", node{1, &node{val: 2}, []string{"a"}}, node{1, &node{val: 2}, []string{"a"}}, node{1, &node{val: 3}, []string{"a"}}},
+		{"nodes with zeros", node{0, &node{}, nil}, node{math.Copysign(0, -1), &node{val: math.Copysign(0, -1)}, nil}, node{0, nil, nil}},
+		{"cyclic nodes", []*node{cyclic}, []*node{otherCyclic}, []*node{{val: 1}}},
+		{"Go maps", map[string][]int{"a": {1}, "b": {2}}, map[string][]int{"b": {2}, "a": {1}}, map[string][]int{"a": {2}, "b": {1}}},
+	}
+	for _, c := range cases {
+		m := NewHashMap(c.key, "a")
+		if got, ok := m.Get(c.equal); !ok || got != "a" {
+			t.Errorf("Case '%s': %#v expected to find the entry for %#v, got %v %v.", c.name, c.equal, c.key, got, ok)
+		}
+		if got, ok := m.Get(c.notEqual); ok {
+			t.Errorf("Case '%s': %#v expected not to find the entry for %#v, got %v.", c.name, c.notEqual, c.key, got)
+		}
+		if m := m.Assoc(c.equal, "b"); m.Count() != 1 {
+			t.Errorf("Case '%s': %#v expected to replace the entry for %#v, got %v.", c.name, c.equal, c.key, m)
+		}
+	}
+}
+
+// Checks that m has exactly the entries in expected, through Count, Get and
+// Iterator, describing the first difference found, if any.
+func checkHashMap(m *HashMap, expected map[interface{}]interface{}) error {
+	if m.Count() != len(expected) {
+		return fmt.Errorf("expected count %d, got %d.", len(expected), m.Count())
+	}
+	for k, v := range expected {
+		if got, ok := m.Get(k); !ok || got != v {
+			return fmt.Errorf("expected %v to map to %v, got %v %v.", k, v, got, ok)
+		}
+	}
+	seen := map[interface{}]bool{}
+	for it := m.Iterator(); it.Next(); {
+		if seen[it.Key()] {
+			return fmt.Errorf("key %v iterated twice.", it.Key())
+		}
+		seen[it.Key()] = true
+		if v, ok := expected[it.Key()]; !ok || v != it.Val() {
+			return fmt.Errorf("unexpected entry %v %v iterated.", it.Key(), it.Val())
+		}
+	}
+	if len(seen) != len(expected) {
+		return fmt.Errorf("expected %d entries iterated, got %d.", len(expected), len(seen))
+	}
+	if _, ok := m.Get(collider{"absent", 42}); ok {
+		return fmt.Errorf("found an absent key.")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package persistent
+
+import "fmt"
+
+// A persistent HashSet is a collection of distinct items that implements
+// almost-constant-time membership test, addition and removal. It is backed by a
+// HashMap whose keys are the items, and so it compares them according to an
+// Equality too.
+// A HashSet value is immutable; every operation on it produces a new, independent
+// value from it. The zero value is an empty set.
+type HashSet struct {
+	impl HashMap
+}
+
+// Makes a new set containing these items. Repeated items are kept once.
+func NewHashSet(items ...interface{}) *HashSet {
+	return NewHashSetWith(nil, items...)
+}
+
+// Makes a new set like NewHashSet, whose items are hashed and compared according
+// to eq. If eq is nil, Go's equality is used, as described in Equality.
+func NewHashSetWith(eq Equality, items ...interface{}) *HashSet {
+	ret := &HashSet{HashMap{eq: eq}}
+	for _, x := range items {
+		ret = ret.Conj(x)
+	}
+	return ret
+}
+
+// Gives the Equality by which the set hashes and compares its items.
+func (s *HashSet) Equality() Equality {
+	return s.impl.Equality()
+}
+
+// Gives the number of items in the set.
+func (s *HashSet) Count() int {
+	return s.impl.Count()
+}
+
+// Tells whether x is in the set.
+func (s *HashSet) Contains(x interface{}) bool {
+	return s.impl.Contains(x)
+}
+
+// Makes a new set with x added to it.
+func (s *HashSet) Conj(x interface{}) *HashSet {
+	if s.Contains(x) {
+		return s
+	}
+	return &HashSet{*s.impl.Assoc(x, x)}
+}
+
+// Makes a new set without x.
+func (s *HashSet) Disj(x interface{}) *HashSet {
+	if !s.Contains(x) {
+		return s
+	}
+	return &HashSet{*s.impl.Dissoc(x)}
+}
+
+// Gives an iterator over the items in the set, in no particular order.
+func (s *HashSet) Iterator() *HashSetIterator {
+	return &HashSetIterator{*s.impl.Iterator()}
+}
+
+// Gives the metadata attached to the set, or nil if it has none.
+func (s *HashSet) Meta() *HashMap {
+	return s.impl.meta
+}
+
+// Makes a new set with the same items as s and meta as its metadata.
+func (s *HashSet) WithMeta(meta *HashMap) *HashSet {
+	return &HashSet{*s.impl.WithMeta(meta)}
+}
+
+func (s *HashSet) String() string {
+	str := "#{"
+	first := true
+	for it := s.Iterator(); it.Next(); {
+		if !first {
+			str += " "
+		}
+		first = false
+		str += fmt.Sprint(it.Item())
+	}
+	str += "}"
+	return str
+}
+
+// A HashSetIterator walks the items of a HashSet. Next must be called before
+// each item is accessed, as in:
+//
+//	for it := s.Iterator(); it.Next(); {
+//		x := it.Item()
+//		...
+//	}
+type HashSetIterator struct {
+	impl HashMapIterator
+}
+
+// Advances to the next item, telling whether there is one.
+func (it *HashSetIterator) Next() bool {
+	return it.impl.Next()
+}
+
+// Gives the current item.
+func (it *HashSetIterator) Item() interface{} {
+	return it.impl.Key()
+}
@@ -0,0 +1,85 @@
+package persistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashSet(t *testing.T) {
+	a1, a2, a3 := collider{"a1", 42}, collider{"a2", 42}, collider{"a3", 42}
+	many := []interface{}{}
+	for i := 0; i < 100; i++ {
+		many = append(many, i)
+	}
+	cases := []struct {
+		name     string
+		s        *HashSet
+		expected []interface{}
+	}{
+		{"zero", &HashSet{}, nil},
+		{"empty", NewHashSet(), nil},
+		{"new", NewHashSet(1, "a", 2), []interface{}{1, "a", 2}},
+		{"new repeated", NewHashSet(1, 1, 2, 1), []interface{}{1, 2}},
+		{"conj", NewHashSet().Conj(1).Conj(2), []interface{}{1, 2}},
+		{"conj present", NewHashSet(1, 2).Conj(1), []interface{}{1, 2}},
+		{"disj", NewHashSet(1, 2, 3).Disj(2), []interface{}{1, 3}},
+		{"disj absent", NewHashSet(1, 2).Disj(3), []interface{}{1, 2}},
+		{"disj to empty", NewHashSet(1).Disj(1), nil},
+		{"nil", NewHashSet(nil, 1), []interface{}{nil, 1}},
+		{"nil conj present", NewHashSet(nil).Conj(nil), []interface{}{nil}},
+		{"nil disj", NewHashSet(nil, 1).Disj(nil), []interface{}{1}},
+		{"collision", NewHashSet(a1, a2, a3), []interface{}{a1, a2, a3}},
+		{"collision conj present", NewHashSet(a1, a2).Conj(a2), []interface{}{a1, a2}},
+		{"collision disj", NewHashSet(a1, a2, a3).Disj(a2), []interface{}{a1, a3}},
+		{"collision disj all", NewHashSet(a1, a2).Disj(a1).Disj(a2), nil},
+		{"many", NewHashSet(many...).Disj(50), append(many[:50:50], many[51:]...)},
+	}
+	for _, c := range cases {
+		if err := checkHashSet(c.s, c.expected); err != nil {
+			t.Errorf("Case '%s': %s", c.name, err)
+		}
+	}
+
+	s := NewHashSet(1, 2)
+	if s.Conj(1) != s || s.Disj(3) != s {
+		t.Errorf("Conj of a present item and Disj of an absent one expected to give the same set.")
+	}
+	s.Conj(3)
+	s.Disj(1)
+	if err := checkHashSet(s, []interface{}{1, 2}); err != nil {
+		t.Errorf("Set expected to be unchanged by Conj and Disj: %s", err)
+	}
+	meta := NewHashMap("a", 1)
+	if s := s.WithMeta(meta).Conj(3).Disj(1); s.Meta() != meta {
+		t.Errorf("Metadata expected to be kept, is %v.", s.Meta())
+	}
+}
+
+// Checks that s has exactly the items in expected, through Count, Contains and
+// Iterator.
+func checkHashSet(s *HashSet, expected []interface{}) error {
+	if s.Count() != len(expected) {
+		return fmt.Errorf("expected count %d, got %d.", len(expected), s.Count())
+	}
+	want := map[interface{}]bool{}
+	for _, x := range expected {
+		if !s.Contains(x) {
+			return fmt.Errorf("expected to contain %v.", x)
+		}
+		want[x] = true
+	}
+	seen := map[interface{}]bool{}
+	for it := s.Iterator(); it.Next(); {
+		if seen[it.Item()] || !want[it.Item()] {
+			return fmt.Errorf("unexpected item %v iterated.", it.Item())
+		}
+		seen[it.Item()] = true
+	}
+	if len(seen) != len(expected) {
+		return fmt.Errorf("expected %d items iterated, got %d.", len(expected), len(seen))
+	}
+	if s.Contains(collider{"absent", 42}) {
+		return fmt.Errorf("contains an absent item.")
+	}
+	return nil
+}
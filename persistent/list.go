@@ -14,6 +14,7 @@ import "fmt"
 type List struct {
 	first interface{}
 	rest  *List
+	meta  *HashMap
 }
 
 // Makes a new List containing these items.
@@ -37,7 +38,21 @@ func (l *List) Rest() *List {
 
 // Makes a new list by prepending an element.
 func (l *List) Cons(x interface{}) *List {
-	return &List{x, l}
+	return &List{x, l, l.Meta()}
+}
+
+// Gives the metadata attached to the list, or nil if it has none.
+func (l *List) Meta() *HashMap {
+	if l == nil {
+		return nil
+	}
+	return l.meta
+}
+
+// Makes a new list with the same elements as l and meta as its metadata. The
+// list must not be empty.
+func (l *List) WithMeta(meta *HashMap) *List {
+	return &List{l.first, l.rest, meta}
 }
 
 func (l *List) String() string {
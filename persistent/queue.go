@@ -0,0 +1,165 @@
+package persistent
+
+// This implementation is practically copied from Clojure's
+// clojure.lang.PersistentQueue.
+
+import "fmt"
+
+// A persistent Queue is a sequential data structure that implements fast
+// appending (inserting at the end) and removal from the start, so that elements
+// come out in the same order they went in.
+// It holds a seq over the elements at the front and a Vector with the elements
+// appended after them. When the front is exhausted, a seq over the rear becomes
+// the new front, without copying it; thus, all operations take amortised
+// constant time.
+// A Queue value is immutable; every operation on it produces a new, independent
+// value from it. The zero value is an empty queue.
+type Queue struct {
+	count int
+	front *vectorSeq
+	rear  *Vector
+	meta  *HashMap
+}
+
+// Makes a new queue containing these items, the first of them at its front.
+func NewQueue(items ...interface{}) *Queue {
+	ret := &Queue{}
+	for _, x := range items {
+		ret = ret.Conj(x)
+	}
+	return ret
+}
+
+// Gives the number of elements in the queue.
+func (q *Queue) Count() int {
+	return q.count
+}
+
+// Gives the element at the front of the queue, or nil if it is empty.
+func (q *Queue) Peek() interface{} {
+	if q.front == nil {
+		return nil
+	}
+	return q.front.first()
+}
+
+// Makes a new queue without the element at its front. An empty queue is given
+// back as is.
+func (q *Queue) Pop() *Queue {
+	if q.front == nil {
+		return q
+	}
+	front, rear := q.front.next(), q.rear
+	if front == nil {
+		front, rear = seqVector(rear), nil
+	}
+	return &Queue{q.count - 1, front, rear, q.meta}
+}
+
+// Makes a new queue, appending x at its end.
+func (q *Queue) Conj(x interface{}) *Queue {
+	if q.front == nil {
+		return &Queue{q.count + 1, seqVector(emptyVector.Conj(x)), nil, q.meta}
+	}
+	rear := q.rear
+	if rear == nil {
+		rear = emptyVector
+	}
+	return &Queue{q.count + 1, q.front, rear.Conj(x), q.meta}
+}
+
+// Gives an iterator over the elements in the queue, from front to end.
+func (q *Queue) Iterator() *QueueIterator {
+	return &QueueIterator{next: q.front, rear: q.rear, i: -1}
+}
+
+// Gives the metadata attached to the queue, or nil if it has none.
+func (q *Queue) Meta() *HashMap {
+	return q.meta
+}
+
+// Makes a new queue with the same elements as q and meta as its metadata.
+func (q *Queue) WithMeta(meta *HashMap) *Queue {
+	return &Queue{q.count, q.front, q.rear, meta}
+}
+
+func (q *Queue) String() string {
+	s := "#queue ["
+	for it := q.Iterator(); it.Next(); {
+		if len(s) > len("#queue [") {
+			s += " "
+		}
+		s += fmt.Sprint(it.Item())
+	}
+	s += "]"
+	return s
+}
+
+// A QueueIterator walks the elements of a Queue in order. Next must be called
+// before each element is accessed, as in:
+//
+//	for it := q.Iterator(); it.Next(); {
+//		x := it.Item()
+//		...
+//	}
+type QueueIterator struct {
+	next *vectorSeq
+	rear *Vector
+	i    int
+	cur  interface{}
+}
+
+// Advances to the next element, telling whether there is one.
+func (it *QueueIterator) Next() bool {
+	if it.next != nil {
+		it.cur = it.next.first()
+		it.next = it.next.next()
+		return true
+	}
+	if it.rear != nil && it.i+1 < it.rear.Count() {
+		it.i++
+		it.cur = it.rear.Nth(it.i)
+		return true
+	}
+	it.cur = nil
+	return false
+}
+
+// Gives the current element.
+func (it *QueueIterator) Item() interface{} {
+	return it.cur
+}
+
+// A vectorSeq is a view of the elements of a Vector from index i on, like the
+// seq Clojure's RT.seq gives for a vector. It keeps the leaf that holds element
+// i, so that walking it takes constant time per element.
+type vectorSeq struct {
+	v    *Vector
+	leaf []interface{}
+	i    int
+}
+
+// Gives a seq over the elements of v, or nil if it is nil or empty.
+func seqVector(v *Vector) *vectorSeq {
+	if v == nil || v.count == 0 {
+		return nil
+	}
+	return &vectorSeq{v, v.arrayFor(0), 0}
+}
+
+func (s *vectorSeq) first() interface{} {
+	return s.leaf[s.i&(vectorNodeLen-1)]
+}
+
+// Gives a seq over the elements after the first, or nil if there are none.
+func (s *vectorSeq) next() *vectorSeq {
+	i := s.i + 1
+	if i >= s.v.count {
+		return nil
+	}
+	leaf := s.leaf
+	if i&(vectorNodeLen-1) == 0 {
+		leaf = s.v.arrayFor(i)
+	}
+	return &vectorSeq{s.v, leaf, i}
+}
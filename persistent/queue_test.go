@@ -0,0 +1,118 @@
+package persistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	cases := []struct {
+		name     string
+		q        *Queue
+		expected []interface{}
+	}{
+		{"zero", &Queue{}, ints(0, 0)},
+		{"empty", NewQueue(), ints(0, 0)},
+		{"new", NewQueue(0, 1, 2), ints(0, 3)},
+		{"conj", NewQueue().Conj(0).Conj(1), ints(0, 2)},
+		{"pop", NewQueue(0, 1, 2).Pop(), ints(1, 3)},
+		{"pop to empty", NewQueue(0).Pop(), ints(0, 0)},
+		{"pop empty", NewQueue().Pop().Pop(), ints(0, 0)},
+		{"pop into rear", NewQueue(0, 1, 2).Pop().Pop(), ints(2, 3)},
+		{"conj after pop", NewQueue(0, 1).Pop().Pop().Conj(2).Conj(3), ints(2, 4)},
+		{"pop across leaves", popN(NewQueue(ints(0, 100)...), 40), ints(40, 100)},
+	}
+	for _, c := range cases {
+		if err := checkQueue(c.q, c.expected); err != nil {
+			t.Errorf("Case '%s': %s", c.name, err)
+		}
+	}
+	if s := NewQueue(1, "a", 2).Pop().Conj(3).String(); s != "#queue [a 2 3]" {
+		t.Errorf("Queue expected to print as '#queue [a 2 3]', printed as '%s'.", s)
+	}
+	meta := NewHashMap("a", 1)
+	if q := NewQueue(1, 2).WithMeta(meta).Pop().Conj(3).Pop(); q.Meta() != meta {
+		t.Errorf("Metadata expected to be kept, is %v.", q.Meta())
+	}
+}
+
+func TestQueuePersistence(t *testing.T) {
+	// Conj onto the same queue twice, once its rear has become the front and
+	// while it has items in both.
+	for _, q := range []*Queue{popN(NewQueue(ints(0, 50)...), 10), popN(NewQueue(ints(0, 50)...), 10).Conj(50)} {
+		expected := ints(10, q.Count()+10)
+		a, b := q.Conj("a"), q.Conj("b")
+		if err := checkQueue(a, append(expected[:len(expected):len(expected)], "a")); err != nil {
+			t.Errorf("First conj: %s", err)
+		}
+		if err := checkQueue(b, append(expected[:len(expected):len(expected)], "b")); err != nil {
+			t.Errorf("Second conj: %s", err)
+		}
+		popN(a, 20)
+		if err := checkQueue(q, expected); err != nil {
+			t.Errorf("Original: %s", err)
+		}
+	}
+
+	// The front is used up and replaced by the rear many times over.
+	q := NewQueue()
+	expected := []interface{}{}
+	for i := 0; i < 1000; i++ {
+		q = q.Conj(i)
+		expected = append(expected, i)
+		if i%3 == 0 {
+			q = q.Pop()
+			expected = expected[1:]
+		}
+		if i%200 == 0 {
+			if err := checkQueue(q, expected); err != nil {
+				t.Fatalf("After %d: %s", i, err)
+			}
+		}
+	}
+}
+
+func TestQueuePopSharesRear(t *testing.T) {
+	q := NewQueue(ints(0, 1000)...).Pop()
+	if q.front == nil || q.front.v.count != 999 || q.rear != nil {
+		t.Fatalf("Rear expected to become the front as is, front is %v and rear %v.", q.front, q.rear)
+	}
+	if err := checkQueue(q, ints(1, 1000)); err != nil {
+		t.Error(err)
+	}
+}
+
+// Pops n elements off q.
+func popN(q *Queue, n int) *Queue {
+	for i := 0; i < n; i++ {
+		q = q.Pop()
+	}
+	return q
+}
+
+// Checks that q has exactly the items in expected, in order, through Count,
+// Iterator, and then Peek and Pop until it is empty.
+func checkQueue(q *Queue, expected []interface{}) error {
+	if q.Count() != len(expected) {
+		return fmt.Errorf("expected count %d, got %d.", len(expected), q.Count())
+	}
+	i := 0
+	for it := q.Iterator(); it.Next(); i++ {
+		if i >= len(expected) || it.Item() != expected[i] {
+			return fmt.Errorf("unexpected item %v iterated at %d.", it.Item(), i)
+		}
+	}
+	if i != len(expected) {
+		return fmt.Errorf("expected %d items iterated, got %d.", len(expected), i)
+	}
+	for i, x := range expected {
+		if got := q.Peek(); got != x {
+			return fmt.Errorf("expected to peek %v after %d pops, got %v.", x, i, got)
+		}
+		q = q.Pop()
+	}
+	if q.Count() != 0 || q.Peek() != nil {
+		return fmt.Errorf("expected to be empty after popping every item, is %v.", q)
+	}
+	return nil
+}
@@ -0,0 +1,485 @@
+package persistent
+
+// This implementation follows Bagwell and Rompf's "RRB-Trees: Efficient Immutable
+// Vectors", with the concatenation algorithm described in L'orange's "Improving
+// RRB-Tree Performance through Transience", like Clojure's core.rrb-vector.
+
+import "fmt"
+
+// An RRBVector is a sequential array-like data structure like Vector, that also
+// implements logarithmic-time concatenation, splitting and insertion at any
+// index.
+// It is implemented as a relaxed radix balanced tree: a Vector's tree in which
+// nodes may hold fewer children than they could, as long as they keep a table
+// with the size of each child. Thus, a Vector can be made an RRBVector in
+// constant time, and so can an RRBVector that is balanced (as those made from a
+// Vector or by appending) be made a Vector.
+// An RRBVector value is immutable; every operation on it produces a new,
+// independent value from it.
+type RRBVector struct {
+	count int
+	shift uint
+	root  vectorNode
+	tail  []interface{}
+}
+
+// When concatenating, nodes are only rebalanced if they take more than
+// rrbExtras nodes over the optimal number for their contents.
+const rrbExtras = 2
+
+// Makes a new RRB vector containing these items.
+func NewRRBVector(items ...interface{}) *RRBVector {
+	return NewVector(items...).AsRRB()
+}
+
+// Makes an RRBVector with the contents of the vector, in constant time.
+func (v *Vector) AsRRB() *RRBVector {
+	return &RRBVector{v.count, v.shift, v.root, v.tail}
+}
+
+// Makes a Vector with the contents of the RRB vector. It takes constant time if
+// the RRB vector is balanced, and linear time otherwise.
+func (r *RRBVector) AsVector() *Vector {
+	if r.count == 0 {
+		return emptyVector
+	}
+	if r.root.sizes == nil && len(r.tail) > 0 && r.tailoff()%vectorNodeLen == 0 {
+		return &Vector{r.count, r.shift, r.root, r.tail, nil}
+	}
+	t := emptyVector.AsTransient()
+	r.eachLeaf(r.root, r.shift, func(leaf []interface{}) {
+		for _, x := range leaf {
+			t.Conj(x)
+		}
+	})
+	for _, x := range r.tail {
+		t.Conj(x)
+	}
+	return t.Persistent()
+}
+
+// Gives the number of elements in the vector.
+func (r *RRBVector) Count() int {
+	return r.count
+}
+
+// Gives the i-th element in the vector. It will panic if i >= r.Count().
+func (r *RRBVector) Nth(i int) interface{} {
+	if i < 0 || i >= r.count {
+		panic(outOfBounds)
+	}
+	if i >= r.tailoff() {
+		return r.tail[i-r.tailoff()]
+	}
+	n := r.root
+	for shift := r.shift; shift > 0; shift -= vectorNodeShift {
+		idx, sub := n.childIndex(shift, i)
+		n, i = n.items[idx].(vectorNode), sub
+	}
+	return n.items[i]
+}
+
+// Make a new vector in which item i has value x. It will panic if i > r.Count().
+func (r *RRBVector) Assoc(i int, x interface{}) *RRBVector {
+	if i < 0 || i > r.count {
+		panic(outOfBounds)
+	}
+	if i == r.count {
+		return r.Conj(x)
+	}
+	if i >= r.tailoff() {
+		newTail := cloneAndSet(r.tail, i-r.tailoff(), x)
+		return &RRBVector{r.count, r.shift, r.root, newTail}
+	}
+	return &RRBVector{r.count, r.shift, rrbAssoc(r.root, r.shift, i, x), r.tail}
+}
+
+// Makes a new vector, appending x at the end.
+func (r *RRBVector) Conj(x interface{}) *RRBVector {
+	if len(r.tail) < vectorNodeLen {
+		newTail := make([]interface{}, len(r.tail)+1)
+		copy(newTail, r.tail)
+		newTail[len(r.tail)] = x
+		return &RRBVector{r.count + 1, r.shift, r.root, newTail}
+	}
+	root, shift := rrbPushLeaf(r.root, r.shift, vectorNode{items: r.tail})
+	return &RRBVector{r.count + 1, shift, root, []interface{}{x}}
+}
+
+// Makes a new vector with the elements of r followed by those of other.
+func (r *RRBVector) Concat(other *RRBVector) *RRBVector {
+	if other.count == 0 {
+		return r
+	} else if r.count == 0 {
+		return other
+	}
+	if other.tailoff() == 0 {
+		ret := r
+		for _, x := range other.tail {
+			ret = ret.Conj(x)
+		}
+		return ret
+	}
+	left, leftShift := r.root, r.shift
+	if len(r.tail) > 0 {
+		left, leftShift = rrbPushLeaf(left, leftShift, vectorNode{items: r.tail})
+	}
+	root, shift := rrbConcatSubTree(left, leftShift, other.root, other.shift, true)
+	if shift == 0 {
+		root, shift = makeRRBNode([]interface{}{root}, vectorNodeShift), vectorNodeShift
+	}
+	root, shift = rrbCollapse(root, shift)
+	return &RRBVector{r.count + other.count, shift, root, other.tail}
+}
+
+// Splits the vector in two: one with the first i elements, and one with the
+// rest. It will panic if i > r.Count().
+func (r *RRBVector) SplitAt(i int) (*RRBVector, *RRBVector) {
+	if i < 0 || i > r.count {
+		panic(outOfBounds)
+	}
+	if i >= r.tailoff() {
+		leftTail := make([]interface{}, i-r.tailoff())
+		copy(leftTail, r.tail)
+		rightTail := make([]interface{}, r.count-i)
+		copy(rightTail, r.tail[i-r.tailoff():])
+		left := (&RRBVector{i, r.shift, r.root, leftTail}).withTail()
+		right := &RRBVector{r.count - i, vectorNodeShift, emptyVectorNode, rightTail}
+		return left, right
+	}
+	left := emptyVector.AsRRB()
+	if i > 0 {
+		root, shift := rrbCollapse(rrbTakeLeft(r.root, r.shift, i), r.shift)
+		left = (&RRBVector{i, shift, root, nil}).withTail()
+	}
+	root, shift := rrbCollapse(rrbDropLeft(r.root, r.shift, i), r.shift)
+	right := &RRBVector{r.count - i, shift, root, r.tail}
+	return left, right
+}
+
+// Makes a new vector with x inserted at index i, moving the elements from i on
+// one position forward. It will panic if i > r.Count().
+func (r *RRBVector) Insert(i int, x interface{}) *RRBVector {
+	left, right := r.SplitAt(i)
+	return left.Conj(x).Concat(right)
+}
+
+func (r *RRBVector) String() string {
+	s := "["
+	for i := 0; i < r.Count(); i++ {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprint(r.Nth(i))
+	}
+	s += "]"
+	return s
+}
+
+func (r *RRBVector) tailoff() int {
+	return r.count - len(r.tail)
+}
+
+// Gives r itself if its tail is not empty, or else an equivalent vector with its
+// last leaf moved to the tail, as Vector requires.
+func (r *RRBVector) withTail() *RRBVector {
+	if len(r.tail) > 0 || r.count == 0 {
+		return r
+	}
+	n := r.root
+	for shift := r.shift; shift > 0; shift -= vectorNodeShift {
+		children := n.children()
+		n = children[len(children)-1].(vectorNode)
+	}
+	rest := r.count - len(n.items)
+	if rest == 0 {
+		return &RRBVector{r.count, vectorNodeShift, emptyVectorNode, n.items}
+	}
+	root, shift := rrbCollapse(rrbTakeLeft(r.root, r.shift, rest), r.shift)
+	return &RRBVector{r.count, shift, root, n.items}
+}
+
+// Calls f with every leaf under node, in order.
+func (r *RRBVector) eachLeaf(node vectorNode, shift uint, f func([]interface{})) {
+	if shift == 0 {
+		f(node.items)
+		return
+	}
+	for _, child := range node.children() {
+		r.eachLeaf(child.(vectorNode), shift-vectorNodeShift, f)
+	}
+}
+
+// Gives the children of a tree node.
+func (n vectorNode) children() []interface{} {
+	if n.sizes != nil {
+		return n.items[:len(n.sizes)]
+	}
+	i := 0
+	for i < len(n.items) && n.items[i] != nil {
+		i++
+	}
+	return n.items[:i]
+}
+
+// Gives the number of elements under a node, which is at level shift.
+func (n vectorNode) size(shift uint) int {
+	if shift == 0 {
+		return len(n.items)
+	}
+	if n.sizes != nil {
+		return n.sizes[len(n.sizes)-1]
+	}
+	children := n.children()
+	if len(children) == 0 {
+		return 0
+	}
+	last := children[len(children)-1].(vectorNode)
+	return (len(children)-1)<<shift + last.size(shift-vectorNodeShift)
+}
+
+// Tells whether the node at level shift holds as many elements as it can.
+func (n vectorNode) full(shift uint) bool {
+	return n.size(shift) == 1<<(shift+vectorNodeShift)
+}
+
+// Gives the index of the child of the node at level shift that holds element i,
+// and the index of that element within the child.
+func (n vectorNode) childIndex(shift uint, i int) (int, int) {
+	if n.sizes == nil {
+		idx := i >> shift
+		return idx, i - idx<<shift
+	}
+	idx := i >> shift
+	for n.sizes[idx] <= i {
+		idx++
+	}
+	if idx == 0 {
+		return 0, i
+	}
+	return idx, i - n.sizes[idx-1]
+}
+
+// Makes a tree node at level shift with these children, keeping a table of sizes
+// only if the node is not balanced.
+func makeRRBNode(children []interface{}, shift uint) vectorNode {
+	ret := vectorNode{items: make([]interface{}, vectorNodeLen)}
+	copy(ret.items, children)
+	balanced := true
+	for i, child := range children {
+		c := child.(vectorNode)
+		if i < len(children)-1 && !c.full(shift-vectorNodeShift) ||
+			i == len(children)-1 && c.sizes != nil {
+			balanced = false
+			break
+		}
+	}
+	if !balanced {
+		ret.sizes = make([]int, len(children))
+		acc := 0
+		for i, child := range children {
+			acc += child.(vectorNode).size(shift - vectorNodeShift)
+			ret.sizes[i] = acc
+		}
+	}
+	return ret
+}
+
+// Removes levels from the top of the tree while they have only one child.
+func rrbCollapse(root vectorNode, shift uint) (vectorNode, uint) {
+	for shift > vectorNodeShift {
+		children := root.children()
+		if len(children) != 1 {
+			break
+		}
+		root, shift = children[0].(vectorNode), shift-vectorNodeShift
+	}
+	return root, shift
+}
+
+func rrbAssoc(node vectorNode, shift uint, i int, x interface{}) vectorNode {
+	ret := vectorNode{sizes: node.sizes, items: make([]interface{}, len(node.items))}
+	copy(ret.items, node.items)
+	if shift == 0 {
+		ret.items[i] = x
+		return ret
+	}
+	idx, sub := node.childIndex(shift, i)
+	ret.items[idx] = rrbAssoc(node.items[idx].(vectorNode), shift-vectorNodeShift, sub, x)
+	return ret
+}
+
+// Appends a leaf to the tree with the given root, growing it if needed.
+func rrbPushLeaf(root vectorNode, shift uint, leaf vectorNode) (vectorNode, uint) {
+	if ret, ok := rrbPushLeafInto(root, shift, leaf); ok {
+		return ret, shift
+	}
+	children := []interface{}{root, newPath(nil, shift, leaf)}
+	return makeRRBNode(children, shift+vectorNodeShift), shift + vectorNodeShift
+}
+
+// Appends a leaf to the tree under node, or returns false if it has no room.
+func rrbPushLeafInto(node vectorNode, shift uint, leaf vectorNode) (vectorNode, bool) {
+	children := node.children()
+	if shift > vectorNodeShift && len(children) > 0 {
+		last := children[len(children)-1].(vectorNode)
+		if newLast, ok := rrbPushLeafInto(last, shift-vectorNodeShift, leaf); ok {
+			newChildren := make([]interface{}, len(children))
+			copy(newChildren, children)
+			newChildren[len(children)-1] = newLast
+			return makeRRBNode(newChildren, shift), true
+		}
+	}
+	if len(children) == vectorNodeLen {
+		return vectorNode{}, false
+	}
+	newChildren := make([]interface{}, len(children)+1)
+	copy(newChildren, children)
+	newChildren[len(children)] = newPath(nil, shift-vectorNodeShift, leaf)
+	return makeRRBNode(newChildren, shift), true
+}
+
+// Gives the tree under node with only its first n elements. n must be positive.
+func rrbTakeLeft(node vectorNode, shift uint, n int) vectorNode {
+	if shift == 0 {
+		ret := make([]interface{}, n)
+		copy(ret, node.items)
+		return vectorNode{items: ret}
+	}
+	idx, sub := node.childIndex(shift, n-1)
+	children := make([]interface{}, idx+1)
+	copy(children, node.children())
+	children[idx] = rrbTakeLeft(children[idx].(vectorNode), shift-vectorNodeShift, sub+1)
+	return makeRRBNode(children, shift)
+}
+
+// Gives the tree under node without its first n elements. n must be less than
+// the number of elements.
+func rrbDropLeft(node vectorNode, shift uint, n int) vectorNode {
+	if n == 0 {
+		return node
+	}
+	if shift == 0 {
+		ret := make([]interface{}, len(node.items)-n)
+		copy(ret, node.items[n:])
+		return vectorNode{items: ret}
+	}
+	idx, sub := node.childIndex(shift, n)
+	all := node.children()
+	children := make([]interface{}, len(all)-idx)
+	copy(children, all[idx:])
+	children[0] = rrbDropLeft(children[0].(vectorNode), shift-vectorNodeShift, sub)
+	return makeRRBNode(children, shift)
+}
+
+// Concatenates the trees under left, at level leftShift, and right, at level
+// rightShift. It gives a node one level above the highest of them, holding one or
+// two nodes, unless top is true and there is only one, which is then given
+// itself.
+func rrbConcatSubTree(left vectorNode, leftShift uint, right vectorNode, rightShift uint, top bool) (vectorNode, uint) {
+	switch {
+	case leftShift > rightShift:
+		lc := left.children()
+		mid, _ := rrbConcatSubTree(lc[len(lc)-1].(vectorNode), leftShift-vectorNodeShift, right, rightShift, false)
+		return rrbRebalance(lc[:len(lc)-1], mid.children(), nil, leftShift, top)
+	case leftShift < rightShift:
+		rc := right.children()
+		mid, _ := rrbConcatSubTree(left, leftShift, rc[0].(vectorNode), rightShift-vectorNodeShift, false)
+		return rrbRebalance(nil, mid.children(), rc[1:], rightShift, top)
+	case leftShift == 0:
+		if top && len(left.items)+len(right.items) <= vectorNodeLen {
+			items := make([]interface{}, 0, len(left.items)+len(right.items))
+			items = append(append(items, left.items...), right.items...)
+			return vectorNode{items: items}, 0
+		}
+		return makeRRBNode([]interface{}{left, right}, vectorNodeShift), vectorNodeShift
+	}
+	lc, rc := left.children(), right.children()
+	mid, _ := rrbConcatSubTree(lc[len(lc)-1].(vectorNode), leftShift-vectorNodeShift,
+		rc[0].(vectorNode), rightShift-vectorNodeShift, false)
+	return rrbRebalance(lc[:len(lc)-1], mid.children(), rc[1:], leftShift, top)
+}
+
+// Redistributes the contents of the nodes in left, mid and right, which are at
+// the level below shift, so that they take not much more nodes than needed. It
+// gives a node at the level above shift holding the resulting one or two nodes at
+// level shift, unless top is true and there is only one, which is then given
+// itself.
+func rrbRebalance(left, mid, right []interface{}, shift uint, top bool) (vectorNode, uint) {
+	all := make([]interface{}, 0, len(left)+len(mid)+len(right))
+	all = append(append(append(all, left...), mid...), right...)
+	childShift := shift - vectorNodeShift
+
+	sizes := make([]int, len(all))
+	slots := func(n vectorNode) []interface{} {
+		if childShift == 0 {
+			return n.items
+		}
+		return n.children()
+	}
+	for i, n := range all {
+		sizes[i] = len(slots(n.(vectorNode)))
+	}
+	plan := rrbConcatPlan(sizes)
+	if len(plan) < len(all) {
+		flat := []interface{}{}
+		for _, n := range all {
+			flat = append(flat, slots(n.(vectorNode))...)
+		}
+		all = all[:0]
+		for _, size := range plan {
+			if childShift == 0 {
+				items := make([]interface{}, size)
+				copy(items, flat)
+				all = append(all, vectorNode{items: items})
+			} else {
+				all = append(all, makeRRBNode(flat[:size], childShift))
+			}
+			flat = flat[size:]
+		}
+	}
+
+	if len(all) <= vectorNodeLen {
+		node := makeRRBNode(all, shift)
+		if top {
+			return node, shift
+		}
+		return makeRRBNode([]interface{}{node}, shift+vectorNodeShift), shift + vectorNodeShift
+	}
+	nodes := []interface{}{
+		makeRRBNode(all[:vectorNodeLen], shift),
+		makeRRBNode(all[vectorNodeLen:], shift),
+	}
+	return makeRRBNode(nodes, shift+vectorNodeShift), shift + vectorNodeShift
+}
+
+// Given the number of slots used in a sequence of nodes, gives the number of
+// slots each node should have once rebalanced. Nodes are merged into their
+// followers until there are at most rrbExtras nodes more than the optimal.
+func rrbConcatPlan(sizes []int) []int {
+	total := 0
+	for _, s := range sizes {
+		total += s
+	}
+	optimal := (total + vectorNodeLen - 1) / vectorNodeLen
+	n := len(sizes)
+	for optimal+rrbExtras < n {
+		i := 0
+		for sizes[i] > vectorNodeLen-rrbExtras/2 {
+			i++
+		}
+		remaining := sizes[i]
+		for remaining > 0 {
+			minSize := remaining + sizes[i+1]
+			if minSize > vectorNodeLen {
+				minSize = vectorNodeLen
+			}
+			sizes[i] = minSize
+			remaining = remaining + sizes[i+1] - minSize
+			i++
+		}
+		copy(sizes[i:n-1], sizes[i+1:n])
+		n--
+	}
+	return sizes[:n]
+}
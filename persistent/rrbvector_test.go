@@ -0,0 +1,162 @@
+package persistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Sizes around where a Vector's tail, leaves and levels fill up.
+var rrbSizes = []int{0, 1, 31, 32, 33, 64, 100, 1055, 1056, 1057, 33825}
+
+func TestRRBVectorConcat(t *testing.T) {
+	for _, n := range rrbSizes {
+		for _, m := range rrbSizes {
+			a, b := NewRRBVector(ints(0, n)...), NewRRBVector(ints(n, n+m)...)
+			ab := a.Concat(b)
+			if err := checkRRBVector(ab, ints(0, n+m)); err != nil {
+				t.Fatalf("Concatenating %d and %d: %s", n, m, err)
+			}
+			// Concatenating a relaxed tree again, on either side.
+			if err := checkRRBVector(ab.Concat(a), append(ints(0, n+m), ints(0, n)...)); err != nil {
+				t.Fatalf("Concatenating %d and %d, and then %d: %s", n, m, n, err)
+			}
+			if err := checkRRBVector(b.Concat(ab), append(ints(n, n+m), ints(0, n+m)...)); err != nil {
+				t.Fatalf("Concatenating %d to %d and %d: %s", m, n, m, err)
+			}
+			if err := checkRRBVector(a, ints(0, n)); err != nil {
+				t.Fatalf("Concatenating %d and %d changed the first: %s", n, m, err)
+			}
+			if err := checkRRBVector(b, ints(n, n+m)); err != nil {
+				t.Fatalf("Concatenating %d and %d changed the second: %s", n, m, err)
+			}
+		}
+	}
+}
+
+func TestRRBVectorSplitAndInsert(t *testing.T) {
+	// A relaxed tree, which needs its size tables to find where an index is.
+	relaxed := NewRRBVector(ints(0, 40)...).Concat(NewRRBVector(ints(40, 1100)...)).Concat(NewRRBVector(ints(1100, 1200)...))
+	vectors := map[string]*RRBVector{"relaxed": relaxed}
+	for _, n := range rrbSizes {
+		vectors[fmt.Sprint(n)] = NewRRBVector(ints(0, n)...)
+	}
+	for name, v := range vectors {
+		n := v.Count()
+		for _, at := range []int{0, 1, 31, 32, 33, 40, 1056, n / 2, n - 1, n} {
+			if at < 0 || at > n {
+				continue
+			}
+			left, right := v.SplitAt(at)
+			if err := checkRRBVector(left, ints(0, at)); err != nil {
+				t.Fatalf("Splitting %s at %d, left: %s", name, at, err)
+			}
+			if err := checkRRBVector(right, ints(at, n)); err != nil {
+				t.Fatalf("Splitting %s at %d, right: %s", name, at, err)
+			}
+			if err := checkRRBVector(right.Concat(left), append(ints(at, n), ints(0, at)...)); err != nil {
+				t.Fatalf("Splitting %s at %d, and swapping: %s", name, at, err)
+			}
+			expected := append(append(ints(0, at), "x"), ints(at, n)...)
+			if err := checkRRBVector(v.Insert(at, "x"), expected); err != nil {
+				t.Fatalf("Inserting into %s at %d: %s", name, at, err)
+			}
+		}
+		if err := checkRRBVector(v, ints(0, n)); err != nil {
+			t.Fatalf("Splitting and inserting changed %s: %s", name, err)
+		}
+	}
+}
+
+func TestRRBVectorManyConcats(t *testing.T) {
+	v := NewRRBVector()
+	expected := []interface{}{}
+	for i := 0; i < 4000; i++ {
+		small := ints(i*100, i*100+1+i%40)
+		v = v.Concat(NewRRBVector(small...))
+		expected = append(expected, small...)
+	}
+	for i := 0; i < 500; i++ {
+		at := (i * 7919) % len(expected)
+		v = v.Insert(at, -i)
+		expected = append(expected[:at], append([]interface{}{-i}, expected[at:]...)...)
+	}
+	if err := checkRRBVector(v, expected); err != nil {
+		t.Fatal(err)
+	}
+	// Concatenation rebalances, so the tree stays as shallow as a Vector's of
+	// the same size.
+	if v.shift > 3*vectorNodeShift {
+		t.Errorf("Tree of %d elements expected to have at most 4 levels, has shift %d.", v.Count(), v.shift)
+	}
+}
+
+func TestRRBVectorAsVector(t *testing.T) {
+	v := NewVector(ints(0, 5000)...)
+	if r := v.AsRRB().AsVector(); r.root.items == nil || &r.tail[0] != &v.tail[0] {
+		t.Errorf("Balanced RRB vector expected to be made a Vector sharing its nodes.")
+	}
+	r := NewRRBVector(ints(0, 40)...).Concat(NewRRBVector(ints(40, 5000)...)).AsVector()
+	if err := checkVector(r, ints(0, 5000)); err != nil {
+		t.Error(err)
+	}
+	if err := checkVector(r.Conj(5000).Pop().Pop(), ints(0, 4999)); err != nil {
+		t.Error(err)
+	}
+
+	// Conj and Assoc on a relaxed tree, past a leaf's worth of items.
+	relaxed := NewRRBVector(ints(0, 40)...).Concat(NewRRBVector(ints(40, 1100)...))
+	for i := 1100; i < 1170; i++ {
+		relaxed = relaxed.Conj(i)
+	}
+	relaxed = relaxed.Assoc(35, "x").Assoc(1150, "y")
+	expected := ints(0, 1170)
+	expected[35], expected[1150] = "x", "y"
+	if err := checkRRBVector(relaxed, expected); err != nil {
+		t.Error(err)
+	}
+	if err := checkVector(relaxed.AsVector(), expected); err != nil {
+		t.Error(err)
+	}
+}
+
+// Checks that r has exactly the items in expected, and that every node's table
+// of sizes, or lack of it, is consistent with its children.
+func checkRRBVector(r *RRBVector, expected []interface{}) error {
+	if r.Count() != len(expected) {
+		return fmt.Errorf("expected count %d, got %d.", len(expected), r.Count())
+	}
+	for i, x := range expected {
+		if got := r.Nth(i); got != x {
+			return fmt.Errorf("expected item %d to be %v, got %v.", i, x, got)
+		}
+	}
+	if r.count > 0 && r.root.size(r.shift) != r.tailoff() {
+		return fmt.Errorf("root holds %d elements, but tail starts at %d.", r.root.size(r.shift), r.tailoff())
+	}
+	return checkRRBNode(r.root, r.shift)
+}
+
+func checkRRBNode(n vectorNode, shift uint) error {
+	if shift == 0 {
+		if len(n.items) > vectorNodeLen {
+			return fmt.Errorf("leaf with %d items.", len(n.items))
+		}
+		return nil
+	}
+	children := n.children()
+	acc := 0
+	for i, child := range children {
+		c := child.(vectorNode)
+		acc += c.size(shift - vectorNodeShift)
+		if n.sizes != nil && n.sizes[i] != acc {
+			return fmt.Errorf("size table %v doesn't match child %d's size.", n.sizes, i)
+		}
+		if n.sizes == nil && i < len(children)-1 && !c.full(shift-vectorNodeShift) {
+			return fmt.Errorf("node without size table has non-full child %d.", i)
+		}
+		if err := checkRRBNode(c, shift-vectorNodeShift); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+package persistent
+
+// This implementation is practically copied from Clojure's
+// clojure.lang.PersistentTreeMap.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A Comparator tells how a is ordered with respect to b: a negative number if a
+// goes before b, zero if they are equivalent, and a positive number if a goes
+// after b.
+type Comparator func(a, b interface{}) int
+
+// A persistent SortedMap is an associative data structure, mapping keys to values,
+// that keeps its entries ordered by key according to a Comparator. It implements
+// logarithmic-time lookup, association and dissociation, and in-order iteration.
+// It is implemented as a red-black tree.
+// A SortedMap value is immutable; every operation on it produces a new, independent
+// value from it. SortedMaps must be made with NewSortedMap.
+type SortedMap struct {
+	comp  Comparator
+	count int
+	tree  *treeNode
+}
+
+var (
+	invalidTree = errors.New("invalid red-black tree")
+)
+
+// Makes a new map, ordered by comp, from keys and values interleaved, as in k1,
+// v1, k2, v2... It will panic if the number of arguments is odd. Later values
+// replace earlier ones with an equivalent key.
+func NewSortedMap(comp Comparator, keyvals ...interface{}) *SortedMap {
+	if len(keyvals)%2 != 0 {
+		panic(oddKeyValues)
+	}
+	ret := &SortedMap{comp: comp}
+	for i := 0; i < len(keyvals); i += 2 {
+		ret = ret.Assoc(keyvals[i], keyvals[i+1])
+	}
+	return ret
+}
+
+// Gives the comparator by which the map is ordered.
+func (m *SortedMap) Comparator() Comparator {
+	return m.comp
+}
+
+// Gives the number of entries in the map.
+func (m *SortedMap) Count() int {
+	return m.count
+}
+
+// Gives the value associated with key, and whether it was found at all.
+func (m *SortedMap) Get(key interface{}) (interface{}, bool) {
+	t := m.tree
+	for t != nil {
+		c := m.comp(key, t.key)
+		if c == 0 {
+			return t.val, true
+		} else if c < 0 {
+			t = t.left
+		} else {
+			t = t.right
+		}
+	}
+	return nil, false
+}
+
+// Tells whether the map holds an entry for key.
+func (m *SortedMap) Contains(key interface{}) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Makes a new map in which key is associated with val.
+func (m *SortedMap) Assoc(key interface{}, val interface{}) *SortedMap {
+	found := false
+	t := m.add(m.tree, key, val, &found)
+	if found {
+		return &SortedMap{m.comp, m.count, m.replace(m.tree, key, val)}
+	}
+	return &SortedMap{m.comp, m.count + 1, t.blacken()}
+}
+
+// Makes a new map without any entry for key.
+func (m *SortedMap) Dissoc(key interface{}) *SortedMap {
+	found := false
+	t := m.remove(m.tree, key, &found)
+	if !found {
+		return m
+	}
+	return &SortedMap{m.comp, m.count - 1, t.blacken()}
+}
+
+// Gives an iterator over the entries in the map, in ascending order of keys.
+func (m *SortedMap) Iterator() *SortedMapIterator {
+	it := &SortedMapIterator{ascending: true}
+	it.push(m.tree)
+	return it
+}
+
+// Gives an iterator over the entries in the map, in descending order of keys.
+func (m *SortedMap) ReverseIterator() *SortedMapIterator {
+	it := &SortedMapIterator{ascending: false}
+	it.push(m.tree)
+	return it
+}
+
+// Gives an iterator over the entries in the map whose keys are equal to or after
+// key, in ascending order.
+func (m *SortedMap) Subseq(key interface{}) *SortedMapIterator {
+	return m.seqFrom(key, true)
+}
+
+// Gives an iterator over the entries in the map whose keys are equal to or before
+// key, in descending order.
+func (m *SortedMap) Rsubseq(key interface{}) *SortedMapIterator {
+	return m.seqFrom(key, false)
+}
+
+func (m *SortedMap) seqFrom(key interface{}, ascending bool) *SortedMapIterator {
+	it := &SortedMapIterator{ascending: ascending}
+	t := m.tree
+	for t != nil {
+		c := m.comp(key, t.key)
+		if c == 0 {
+			it.stack = append(it.stack, t)
+			break
+		} else if (ascending && c < 0) || (!ascending && c > 0) {
+			it.stack = append(it.stack, t)
+			if ascending {
+				t = t.left
+			} else {
+				t = t.right
+			}
+		} else if ascending {
+			t = t.right
+		} else {
+			t = t.left
+		}
+	}
+	return it
+}
+
+func (m *SortedMap) String() string {
+	s := "{"
+	for it := m.Iterator(); it.Next(); {
+		if len(s) > 1 {
+			s += ", "
+		}
+		s += fmt.Sprint(it.Key()) + " " + fmt.Sprint(it.Val())
+	}
+	s += "}"
+	return s
+}
+
+// A SortedMapIterator walks the entries of a SortedMap in order. Next must be
+// called before each entry is accessed, as in:
+//
+//	for it := m.Iterator(); it.Next(); {
+//		k, v := it.Key(), it.Val()
+//		...
+//	}
+type SortedMapIterator struct {
+	stack     []*treeNode
+	ascending bool
+	cur       *treeNode
+}
+
+// Advances to the next entry, telling whether there is one.
+func (it *SortedMapIterator) Next() bool {
+	if len(it.stack) == 0 {
+		it.cur = nil
+		return false
+	}
+	it.cur = it.stack[len(it.stack)-1]
+	it.stack = it.stack[:len(it.stack)-1]
+	if it.ascending {
+		it.push(it.cur.right)
+	} else {
+		it.push(it.cur.left)
+	}
+	return true
+}
+
+// Gives the key of the current entry.
+func (it *SortedMapIterator) Key() interface{} {
+	return it.cur.key
+}
+
+// Gives the value of the current entry.
+func (it *SortedMapIterator) Val() interface{} {
+	return it.cur.val
+}
+
+// Pushes t and the nodes in its leftmost path (rightmost if descending).
+func (it *SortedMapIterator) push(t *treeNode) {
+	for t != nil {
+		it.stack = append(it.stack, t)
+		if it.ascending {
+			t = t.left
+		} else {
+			t = t.right
+		}
+	}
+}
+
+// SortedMaps are implemented as red-black trees of treeNodes, each holding an
+// entry. Nodes are never modified once made.
+type treeNode struct {
+	key, val    interface{}
+	left, right *treeNode
+	red         bool
+}
+
+func red(key, val interface{}, left, right *treeNode) *treeNode {
+	return &treeNode{key, val, left, right, true}
+}
+
+func black(key, val interface{}, left, right *treeNode) *treeNode {
+	return &treeNode{key, val, left, right, false}
+}
+
+// Unlike a plain !t.red, isBlack is false for empty trees.
+func isBlack(t *treeNode) bool {
+	return t != nil && !t.red
+}
+
+func isRed(t *treeNode) bool {
+	return t != nil && t.red
+}
+
+func (t *treeNode) blacken() *treeNode {
+	if !isRed(t) {
+		return t
+	}
+	return black(t.key, t.val, t.left, t.right)
+}
+
+func (t *treeNode) redden() *treeNode {
+	if t.red {
+		panic(invalidTree)
+	}
+	return red(t.key, t.val, t.left, t.right)
+}
+
+func (t *treeNode) addLeft(ins *treeNode) *treeNode {
+	if t.red {
+		return red(t.key, t.val, ins, t.right)
+	}
+	return ins.balanceLeft(t)
+}
+
+func (t *treeNode) addRight(ins *treeNode) *treeNode {
+	if t.red {
+		return red(t.key, t.val, t.left, ins)
+	}
+	return ins.balanceRight(t)
+}
+
+func (t *treeNode) balanceLeft(parent *treeNode) *treeNode {
+	if t.red {
+		if isRed(t.left) {
+			return red(t.key, t.val, t.left.blacken(), black(parent.key, parent.val, t.right, parent.right))
+		} else if isRed(t.right) {
+			return red(t.right.key, t.right.val,
+				black(t.key, t.val, t.left, t.right.left),
+				black(parent.key, parent.val, t.right.right, parent.right))
+		}
+	}
+	return black(parent.key, parent.val, t, parent.right)
+}
+
+func (t *treeNode) balanceRight(parent *treeNode) *treeNode {
+	if t.red {
+		if isRed(t.right) {
+			return red(t.key, t.val, black(parent.key, parent.val, parent.left, t.left), t.right.blacken())
+		} else if isRed(t.left) {
+			return red(t.left.key, t.left.val,
+				black(parent.key, parent.val, parent.left, t.left.left),
+				black(t.key, t.val, t.left.right, t.right))
+		}
+	}
+	return black(parent.key, parent.val, parent.left, t)
+}
+
+// Gives the tree t with key added, or sets found and returns nil if it was
+// already there.
+func (m *SortedMap) add(t *treeNode, key, val interface{}, found *bool) *treeNode {
+	if t == nil {
+		return red(key, val, nil, nil)
+	}
+	c := m.comp(key, t.key)
+	if c == 0 {
+		*found = true
+		return nil
+	}
+	if c < 0 {
+		ins := m.add(t.left, key, val, found)
+		if ins == nil {
+			return nil
+		}
+		return t.addLeft(ins)
+	}
+	ins := m.add(t.right, key, val, found)
+	if ins == nil {
+		return nil
+	}
+	return t.addRight(ins)
+}
+
+// Gives the tree t, which must hold key, with val as its value.
+func (m *SortedMap) replace(t *treeNode, key, val interface{}) *treeNode {
+	c := m.comp(key, t.key)
+	ret := &treeNode{t.key, t.val, t.left, t.right, t.red}
+	if c == 0 {
+		ret.val = val
+	} else if c < 0 {
+		ret.left = m.replace(t.left, key, val)
+	} else {
+		ret.right = m.replace(t.right, key, val)
+	}
+	return ret
+}
+
+// Gives the tree t without key, setting found if it was there.
+func (m *SortedMap) remove(t *treeNode, key interface{}, found *bool) *treeNode {
+	if t == nil {
+		return nil
+	}
+	c := m.comp(key, t.key)
+	if c == 0 {
+		*found = true
+		return appendTrees(t.left, t.right)
+	}
+	if c < 0 {
+		del := m.remove(t.left, key, found)
+		if !*found {
+			return t
+		}
+		if isBlack(t.left) {
+			return balanceLeftDel(t.key, t.val, del, t.right)
+		}
+		return red(t.key, t.val, del, t.right)
+	}
+	del := m.remove(t.right, key, found)
+	if !*found {
+		return t
+	}
+	if isBlack(t.right) {
+		return balanceRightDel(t.key, t.val, t.left, del)
+	}
+	return red(t.key, t.val, t.left, del)
+}
+
+func appendTrees(left, right *treeNode) *treeNode {
+	if left == nil {
+		return right
+	} else if right == nil {
+		return left
+	} else if isRed(left) {
+		if isRed(right) {
+			app := appendTrees(left.right, right.left)
+			if isRed(app) {
+				return red(app.key, app.val,
+					red(left.key, left.val, left.left, app.left),
+					red(right.key, right.val, app.right, right.right))
+			}
+			return red(left.key, left.val, left.left, red(right.key, right.val, app, right.right))
+		}
+		return red(left.key, left.val, left.left, appendTrees(left.right, right))
+	} else if isRed(right) {
+		return red(right.key, right.val, appendTrees(left, right.left), right.right)
+	}
+	// Both are black.
+	app := appendTrees(left.right, right.left)
+	if isRed(app) {
+		return red(app.key, app.val,
+			black(left.key, left.val, left.left, app.left),
+			black(right.key, right.val, app.right, right.right))
+	}
+	return balanceLeftDel(left.key, left.val, left.left, black(right.key, right.val, app, right.right))
+}
+
+func balanceLeftDel(key, val interface{}, del, right *treeNode) *treeNode {
+	if isRed(del) {
+		return red(key, val, del.blacken(), right)
+	} else if isBlack(right) {
+		return rightBalance(key, val, del, right.redden())
+	} else if isRed(right) && isBlack(right.left) {
+		return red(right.left.key, right.left.val,
+			black(key, val, del, right.left.left),
+			rightBalance(right.key, right.val, right.left.right, right.right.redden()))
+	}
+	panic(invalidTree)
+}
+
+func balanceRightDel(key, val interface{}, left, del *treeNode) *treeNode {
+	if isRed(del) {
+		return red(key, val, left, del.blacken())
+	} else if isBlack(left) {
+		return leftBalance(key, val, left.redden(), del)
+	} else if isRed(left) && isBlack(left.right) {
+		return red(left.right.key, left.right.val,
+			leftBalance(left.key, left.val, left.left.redden(), left.right.left),
+			black(key, val, left.right.right, del))
+	}
+	panic(invalidTree)
+}
+
+func leftBalance(key, val interface{}, ins, right *treeNode) *treeNode {
+	if isRed(ins) && isRed(ins.left) {
+		return red(ins.key, ins.val, ins.left.blacken(), black(key, val, ins.right, right))
+	} else if isRed(ins) && isRed(ins.right) {
+		return red(ins.right.key, ins.right.val,
+			black(ins.key, ins.val, ins.left, ins.right.left),
+			black(key, val, ins.right.right, right))
+	}
+	return black(key, val, ins, right)
+}
+
+func rightBalance(key, val interface{}, left, ins *treeNode) *treeNode {
+	if isRed(ins) && isRed(ins.right) {
+		return red(ins.key, ins.val, black(key, val, left, ins.left), ins.right.blacken())
+	} else if isRed(ins) && isRed(ins.left) {
+		return red(ins.left.key, ins.left.val,
+			black(key, val, left, ins.left.left),
+			black(ins.key, ins.val, ins.left.right, ins.right))
+	}
+	return black(key, val, left, ins)
+}
@@ -0,0 +1,194 @@
+package persistent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func intComparator(a, b interface{}) int {
+	return a.(int) - b.(int)
+}
+
+func reverseComparator(a, b interface{}) int {
+	return b.(int) - a.(int)
+}
+
+func TestSortedMapBalance(t *testing.T) {
+	const n = 500
+	// Inserting keys in order, backwards or scattered, and then deleting every
+	// other one in any of those orders, makes the tree rotate and recolor in
+	// every direction.
+	orders := map[string]func(i int) int{
+		"ascending":  func(i int) int { return i },
+		"descending": func(i int) int { return n - 1 - i },
+		"scattered":  func(i int) int { return i * 7919 % n },
+	}
+	for _, comp := range []Comparator{intComparator, reverseComparator} {
+		for insertName, insert := range orders {
+			for deleteName, del := range orders {
+				m := NewSortedMap(comp)
+				expected := map[int]int{}
+				for i := 0; i < n; i++ {
+					m = m.Assoc(insert(i), i)
+					expected[insert(i)] = i
+					if err := checkRedBlack(m.tree, comp); err != nil {
+						t.Fatalf("Inserting %s, after %d: %v", insertName, i+1, err)
+					}
+				}
+				for i := 0; i < n; i += 2 {
+					m = m.Dissoc(del(i))
+					delete(expected, del(i))
+					if err := checkRedBlack(m.tree, comp); err != nil {
+						t.Fatalf("Inserting %s and deleting %s, after %d: %v", insertName, deleteName, i/2+1, err)
+					}
+				}
+				if err := checkSortedMap(m, expected); err != nil {
+					t.Errorf("Inserting %s and deleting %s: %s", insertName, deleteName, err)
+				}
+			}
+		}
+	}
+}
+
+func TestSortedMapSubseq(t *testing.T) {
+	m := NewSortedMap(intComparator)
+	for _, k := range []int{10, 2, 8, 4, 6} {
+		m = m.Assoc(k, -k)
+	}
+	cases := []struct {
+		key       int
+		ascending bool
+		expected  string
+	}{
+		{0, true, "2 4 6 8 10"},
+		{4, true, "4 6 8 10"},
+		{5, true, "6 8 10"},
+		{10, true, "10"},
+		{11, true, ""},
+		{11, false, "10 8 6 4 2"},
+		{6, false, "6 4 2"},
+		{5, false, "4 2"},
+		{2, false, "2"},
+		{1, false, ""},
+	}
+	for _, c := range cases {
+		it := m.Subseq(c.key)
+		if !c.ascending {
+			it = m.Rsubseq(c.key)
+		}
+		keys := []string{}
+		for it.Next() {
+			if it.Val() != -it.Key().(int) {
+				t.Errorf("Key %v iterated with value %v.", it.Key(), it.Val())
+			}
+			keys = append(keys, fmt.Sprint(it.Key()))
+		}
+		if got := strings.Join(keys, " "); got != c.expected {
+			t.Errorf("Seq from %d, ascending %v: expected '%s', got '%s'.", c.key, c.ascending, c.expected, got)
+		}
+	}
+
+	keys := []string{}
+	for it := m.ReverseIterator(); it.Next(); {
+		keys = append(keys, fmt.Sprint(it.Key()))
+	}
+	if got := strings.Join(keys, " "); got != "10 8 6 4 2" {
+		t.Errorf("Reverse iterator expected to give '10 8 6 4 2', gave '%s'.", got)
+	}
+}
+
+func TestSortedSet(t *testing.T) {
+	s := NewSortedSet(reverseComparator, 3, 1, 2, 3, 5)
+	if s.Count() != 4 || !s.Contains(5) || s.Contains(4) {
+		t.Errorf("Bad set %v.", s)
+	}
+	s2 := s.Disj(3).Conj(4).Disj(7)
+	cases := []struct {
+		set      *SortedSet
+		it       *SortedSetIterator
+		expected string
+	}{
+		{s, s.Iterator(), "5 3 2 1"},
+		{s2, s2.Iterator(), "5 4 2 1"},
+		{s2, s2.ReverseIterator(), "1 2 4 5"},
+		{s2, s2.Subseq(3), "2 1"},
+		{s2, s2.Rsubseq(3), "4 5"},
+	}
+	for _, c := range cases {
+		items := []string{}
+		for c.it.Next() {
+			items = append(items, fmt.Sprint(c.it.Item()))
+		}
+		if got := strings.Join(items, " "); got != c.expected {
+			t.Errorf("Iterating %v expected to give '%s', gave '%s'.", c.set, c.expected, got)
+		}
+	}
+}
+
+// Checks the invariants of a red-black tree: keys are in order, no red node
+// has a red child, and every path from the root has the same number of black
+// nodes.
+func checkRedBlack(root *treeNode, comp Comparator) error {
+	if isRed(root) {
+		return fmt.Errorf("red root.")
+	}
+	_, err := blackHeight(root, comp, nil, nil)
+	return err
+}
+
+// Gives the number of black nodes in every path from t to a leaf, checking that
+// its keys are between min and max, if not nil.
+func blackHeight(t *treeNode, comp Comparator, min, max interface{}) (int, error) {
+	if t == nil {
+		return 1, nil
+	}
+	if min != nil && comp(t.key, min) <= 0 || max != nil && comp(t.key, max) >= 0 {
+		return 0, fmt.Errorf("key %v out of order.", t.key)
+	}
+	if t.red && (isRed(t.left) || isRed(t.right)) {
+		return 0, fmt.Errorf("red node %v with a red child.", t.key)
+	}
+	left, err := blackHeight(t.left, comp, min, t.key)
+	if err != nil {
+		return 0, err
+	}
+	right, err := blackHeight(t.right, comp, t.key, max)
+	if err != nil {
+		return 0, err
+	}
+	if left != right {
+		return 0, fmt.Errorf("node %v has black heights %d and %d.", t.key, left, right)
+	}
+	if !t.red {
+		left++
+	}
+	return left, nil
+}
+
+// Checks that m has exactly the entries in expected, in the order of its
+// comparator.
+func checkSortedMap(m *SortedMap, expected map[int]int) error {
+	if m.Count() != len(expected) {
+		return fmt.Errorf("expected count %d, got %d.", len(expected), m.Count())
+	}
+	keys := []int{}
+	for k, v := range expected {
+		if got, ok := m.Get(k); !ok || got != v {
+			return fmt.Errorf("expected %v to map to %v, got %v %v.", k, v, got, ok)
+		}
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return m.Comparator()(keys[i], keys[j]) < 0 })
+	i := 0
+	for it := m.Iterator(); it.Next(); i++ {
+		if i >= len(keys) || it.Key() != keys[i] {
+			return fmt.Errorf("unexpected key %v iterated at %d.", it.Key(), i)
+		}
+	}
+	if i != len(keys) {
+		return fmt.Errorf("expected %d entries iterated, got %d.", len(keys), i)
+	}
+	return nil
+}
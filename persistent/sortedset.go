@@ -0,0 +1,109 @@
+package persistent
+
+import "fmt"
+
+// A persistent SortedSet is a collection of distinct items kept ordered according
+// to a Comparator. It implements logarithmic-time membership test, addition and
+// removal, and in-order iteration. It is backed by a SortedMap whose keys are the
+// items.
+// A SortedSet value is immutable; every operation on it produces a new, independent
+// value from it. SortedSets must be made with NewSortedSet.
+type SortedSet struct {
+	impl SortedMap
+}
+
+// Makes a new set, ordered by comp, containing these items. Repeated items are
+// kept once.
+func NewSortedSet(comp Comparator, items ...interface{}) *SortedSet {
+	ret := &SortedSet{SortedMap{comp: comp}}
+	for _, x := range items {
+		ret = ret.Conj(x)
+	}
+	return ret
+}
+
+// Gives the comparator by which the set is ordered.
+func (s *SortedSet) Comparator() Comparator {
+	return s.impl.comp
+}
+
+// Gives the number of items in the set.
+func (s *SortedSet) Count() int {
+	return s.impl.Count()
+}
+
+// Tells whether x is in the set.
+func (s *SortedSet) Contains(x interface{}) bool {
+	return s.impl.Contains(x)
+}
+
+// Makes a new set with x added to it.
+func (s *SortedSet) Conj(x interface{}) *SortedSet {
+	if s.Contains(x) {
+		return s
+	}
+	return &SortedSet{*s.impl.Assoc(x, x)}
+}
+
+// Makes a new set without x.
+func (s *SortedSet) Disj(x interface{}) *SortedSet {
+	if !s.Contains(x) {
+		return s
+	}
+	return &SortedSet{*s.impl.Dissoc(x)}
+}
+
+// Gives an iterator over the items in the set, in ascending order.
+func (s *SortedSet) Iterator() *SortedSetIterator {
+	return &SortedSetIterator{*s.impl.Iterator()}
+}
+
+// Gives an iterator over the items in the set, in descending order.
+func (s *SortedSet) ReverseIterator() *SortedSetIterator {
+	return &SortedSetIterator{*s.impl.ReverseIterator()}
+}
+
+// Gives an iterator over the items in the set equal to or after x, in ascending
+// order.
+func (s *SortedSet) Subseq(x interface{}) *SortedSetIterator {
+	return &SortedSetIterator{*s.impl.Subseq(x)}
+}
+
+// Gives an iterator over the items in the set equal to or before x, in
+// descending order.
+func (s *SortedSet) Rsubseq(x interface{}) *SortedSetIterator {
+	return &SortedSetIterator{*s.impl.Rsubseq(x)}
+}
+
+func (s *SortedSet) String() string {
+	str := "#{"
+	for it := s.Iterator(); it.Next(); {
+		if len(str) > 2 {
+			str += " "
+		}
+		str += fmt.Sprint(it.Item())
+	}
+	str += "}"
+	return str
+}
+
+// A SortedSetIterator walks the items of a SortedSet in order. Next must be called
+// before each item is accessed, as in:
+//
+//	for it := s.Iterator(); it.Next(); {
+//		x := it.Item()
+//		...
+//	}
+type SortedSetIterator struct {
+	impl SortedMapIterator
+}
+
+// Advances to the next item, telling whether there is one.
+func (it *SortedSetIterator) Next() bool {
+	return it.impl.Next()
+}
+
+// Gives the current item.
+func (it *SortedSetIterator) Item() interface{} {
+	return it.impl.Key()
+}
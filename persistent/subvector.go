@@ -0,0 +1,85 @@
+package persistent
+
+// This implementation is practically copied from Clojure's
+// clojure.lang.APersistentVector.SubVector.
+
+import "fmt"
+
+// A SubVector is a view of a range of a Vector, made in constant time by
+// Vector.Subvec. It shares structure with the Vector, and supports the same
+// operations.
+// A SubVector value is immutable; every operation on it produces a new, independent
+// value from it.
+type SubVector struct {
+	v          *Vector
+	start, end int
+}
+
+// Gives the number of elements in the subvector.
+func (sv *SubVector) Count() int {
+	return sv.end - sv.start
+}
+
+// Gives the i-th element in the subvector. It will panic if i >= sv.Count().
+func (sv *SubVector) Nth(i int) interface{} {
+	if i < 0 || sv.start+i >= sv.end {
+		panic(outOfBounds)
+	}
+	return sv.v.Nth(sv.start + i)
+}
+
+// Make a new subvector in which item i has value x. It will panic if
+// i > sv.Count().
+func (sv *SubVector) Assoc(i int, x interface{}) *SubVector {
+	if i < 0 || sv.start+i > sv.end {
+		panic(outOfBounds)
+	}
+	if sv.start+i == sv.end {
+		return sv.Conj(x)
+	}
+	return &SubVector{sv.v.Assoc(sv.start+i, x), sv.start, sv.end}
+}
+
+// Makes a new subvector, appending x at the end.
+func (sv *SubVector) Conj(x interface{}) *SubVector {
+	return &SubVector{sv.v.Assoc(sv.end, x), sv.start, sv.end + 1}
+}
+
+// Gives the last element in the subvector, or nil if it is empty.
+func (sv *SubVector) Peek() interface{} {
+	if sv.end == sv.start {
+		return nil
+	}
+	return sv.v.Nth(sv.end - 1)
+}
+
+// Makes a new subvector without the last element. It will panic if the subvector
+// is empty.
+func (sv *SubVector) Pop() *SubVector {
+	if sv.end == sv.start {
+		panic(popEmpty)
+	}
+	return &SubVector{sv.v, sv.start, sv.end - 1}
+}
+
+// Makes a subvector with the elements from start (inclusive) to end (exclusive),
+// sharing structure with sv. It will panic if the bounds are not such that
+// 0 <= start <= end <= sv.Count().
+func (sv *SubVector) Subvec(start, end int) *SubVector {
+	if start < 0 || end > sv.Count() || start > end {
+		panic(outOfBounds)
+	}
+	return &SubVector{sv.v, sv.start + start, sv.start + end}
+}
+
+func (sv *SubVector) String() string {
+	s := "["
+	for i := 0; i < sv.Count(); i++ {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprint(sv.Nth(i))
+	}
+	s += "]"
+	return s
+}
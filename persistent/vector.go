@@ -23,20 +23,22 @@ type Vector struct {
 	shift uint
 	root  vectorNode
 	tail  []interface{}
+	meta  *HashMap
 }
 
 var (
-	outOfBounds = errors.New("index of out bounds")
+	outOfBounds              = errors.New("index of out bounds")
+	transientAfterPersistent = errors.New("transient used after Persistent call")
+	popEmpty                 = errors.New("can't pop empty vector")
 )
 
 // Makes a new vector containing these items.
 func NewVector(items ...interface{}) *Vector {
-	// ret := emptyVector.AsTransient()
-	ret := &Vector{0, vectorNodeShift, emptyVectorNode, []interface{}{}}
+	ret := emptyVector.AsTransient()
 	for _, x := range items {
 		ret = ret.Conj(x)
 	}
-	return ret
+	return ret.Persistent()
 }
 
 // Gives the number of elements in the vector.
@@ -61,10 +63,10 @@ func (v *Vector) Assoc(i int, x interface{}) *Vector {
 	if i >= v.tailoff() {
 		newTail := make([]interface{}, len(v.tail))
 		copy(newTail, v.tail)
-		newTail[i&(1<<(v.shift-1))] = x
-		return &Vector{v.count, v.shift, v.root, newTail}
+		newTail[i&(vectorNodeLen-1)] = x
+		return &Vector{v.count, v.shift, v.root, newTail, v.meta}
 	}
-	return &Vector{v.count, v.shift, doAssoc(v.shift, v.root, i, x), v.tail}
+	return &Vector{v.count, v.shift, doAssoc(v.shift, v.root, i, x), v.tail, v.meta}
 }
 
 // Makes a new vector, appending x at the end.
@@ -73,20 +75,75 @@ func (v *Vector) Conj(x interface{}) *Vector {
 		newTail := make([]interface{}, len(v.tail)+1)
 		copy(newTail, v.tail)
 		newTail[len(v.tail)] = x
-		return &Vector{v.count + 1, v.shift, v.root, newTail}
+		return &Vector{v.count + 1, v.shift, v.root, newTail, v.meta}
 	}
 	newRoot := vectorNode{}
-	tailNode := vectorNode{v.tail}
+	tailNode := vectorNode{items: v.tail}
 	newShift := v.shift
 	if (v.count >> vectorNodeShift) > (1 << v.shift) {
-		newRoot = vectorNode{make([]interface{}, vectorNodeLen)}
+		newRoot = vectorNode{items: make([]interface{}, vectorNodeLen)}
 		newRoot.items[0] = v.root
-		newRoot.items[1] = newPath(v.shift, tailNode)
+		newRoot.items[1] = newPath(nil, v.shift, tailNode)
 		newShift += vectorNodeShift
 	} else {
 		newRoot = v.pushTail(v.shift, v.root, tailNode)
 	}
-	return &Vector{v.count + 1, newShift, newRoot, []interface{}{x}}
+	return &Vector{v.count + 1, newShift, newRoot, []interface{}{x}, v.meta}
+}
+
+// Gives the last element in the vector, or nil if it is empty.
+func (v *Vector) Peek() interface{} {
+	if v.count == 0 {
+		return nil
+	}
+	return v.Nth(v.count - 1)
+}
+
+// Makes a new vector without the last element. It will panic if the vector is
+// empty.
+func (v *Vector) Pop() *Vector {
+	if v.count == 0 {
+		panic(popEmpty)
+	}
+	if v.count == 1 {
+		return emptyVector.WithMeta(v.meta)
+	}
+	if v.count-v.tailoff() > 1 {
+		newTail := make([]interface{}, len(v.tail)-1)
+		copy(newTail, v.tail)
+		return &Vector{v.count - 1, v.shift, v.root, newTail, v.meta}
+	}
+	newTail := v.arrayFor(v.count - 2)
+	newRoot, ok := v.popTail(v.shift, v.root)
+	newShift := v.shift
+	if !ok {
+		newRoot = emptyVectorNode
+	}
+	if v.shift > vectorNodeShift && newRoot.items[1] == nil {
+		newRoot = newRoot.items[0].(vectorNode)
+		newShift -= vectorNodeShift
+	}
+	return &Vector{v.count - 1, newShift, newRoot, newTail, v.meta}
+}
+
+// Makes a vector with the elements from start (inclusive) to end (exclusive) in
+// constant time, sharing structure with v. It will panic if the bounds are not
+// such that 0 <= start <= end <= v.Count().
+func (v *Vector) Subvec(start, end int) *SubVector {
+	if start < 0 || end > v.count || start > end {
+		panic(outOfBounds)
+	}
+	return &SubVector{v, start, end}
+}
+
+// Gives the metadata attached to the vector, or nil if it has none.
+func (v *Vector) Meta() *HashMap {
+	return v.meta
+}
+
+// Makes a new vector with the same elements as v and meta as its metadata.
+func (v *Vector) WithMeta(meta *HashMap) *Vector {
+	return &Vector{v.count, v.shift, v.root, v.tail, meta}
 }
 
 func (v *Vector) String() string {
@@ -126,13 +183,25 @@ func (v *Vector) StringRaw() string {
 }
 
 var emptyVectorNode = vectorNode{items: make([]interface{}, vectorNodeLen)}
-var emptyVector = &Vector{0, vectorNodeShift, emptyVectorNode, []interface{}{}}
+var emptyVector = &Vector{0, vectorNodeShift, emptyVectorNode, []interface{}{}, nil}
 
 // Vectors are implemented as tree structures. Each node (vectorNode) is either a
 // tree, in which case items will be an array of nodes, or a leaf, in which case
 // items will be an array holding the contents of a chunk of the vector.
+// Nodes made by a TransientVector are tagged with its edit, which allows the
+// transient to modify them in place.
+// Tree nodes in an RRBVector may also hold fewer children than they could; those
+// keep in sizes the accumulated number of elements under each child.
 type vectorNode struct {
+	edit  *vectorEdit
 	items []interface{}
+	sizes []int
+}
+
+// A vectorEdit identifies the TransientVector that owns a node. It stops being
+// active when the transient is made persistent.
+type vectorEdit struct {
+	active bool
 }
 
 func (v *Vector) tailoff() int {
@@ -151,7 +220,7 @@ func (v *Vector) arrayFor(i int) []interface{} {
 	}
 	n := v.root
 	for level := v.shift; level > 0; level -= vectorNodeShift {
-		n = n.items[(i>>level)&((vectorNodeShift)-1)].(vectorNode)
+		n = n.items[(i>>level)&(vectorNodeLen-1)].(vectorNode)
 	}
 	return n.items
 }
@@ -168,9 +237,35 @@ func doAssoc(shift uint, node vectorNode, i int, x interface{}) vectorNode {
 	return ret
 }
 
+// Removes the last leaf from the tree under node. The returned bool is false if
+// no node is left.
+func (v *Vector) popTail(shift uint, node vectorNode) (vectorNode, bool) {
+	subi := ((v.count - 2) >> shift) & (vectorNodeLen - 1)
+	if shift > vectorNodeShift {
+		newChild, ok := v.popTail(shift-vectorNodeShift, node.items[subi].(vectorNode))
+		if !ok && subi == 0 {
+			return vectorNode{}, false
+		}
+		ret := vectorNode{items: make([]interface{}, len(node.items))}
+		copy(ret.items, node.items)
+		if ok {
+			ret.items[subi] = newChild
+		} else {
+			ret.items[subi] = nil
+		}
+		return ret, true
+	} else if subi == 0 {
+		return vectorNode{}, false
+	}
+	ret := vectorNode{items: make([]interface{}, len(node.items))}
+	copy(ret.items, node.items)
+	ret.items[subi] = nil
+	return ret, true
+}
+
 func (v *Vector) pushTail(shift uint, parent vectorNode, tailNode vectorNode) vectorNode {
 	subi := ((v.count - 1) >> shift) & (vectorNodeLen - 1)
-	ret := vectorNode{make([]interface{}, len(parent.items))}
+	ret := vectorNode{items: make([]interface{}, len(parent.items))}
 	copy(ret.items, parent.items)
 	nodeToInsert := vectorNode{}
 	if shift == vectorNodeShift {
@@ -180,37 +275,227 @@ func (v *Vector) pushTail(shift uint, parent vectorNode, tailNode vectorNode) ve
 		if ok {
 			nodeToInsert = v.pushTail(shift-vectorNodeShift, child, tailNode)
 		} else {
-			nodeToInsert = newPath(shift-vectorNodeShift, tailNode)
+			nodeToInsert = newPath(nil, shift-vectorNodeShift, tailNode)
 		}
 	}
 	ret.items[subi] = nodeToInsert
 	return ret
 }
 
-func newPath(shift uint, node vectorNode) vectorNode {
+func newPath(edit *vectorEdit, shift uint, node vectorNode) vectorNode {
 	if shift == 0 {
 		return node
 	}
-	ret := vectorNode{make([]interface{}, vectorNodeLen)}
-	ret.items[0] = newPath(shift-vectorNodeShift, node)
+	ret := vectorNode{edit: edit, items: make([]interface{}, vectorNodeLen)}
+	ret.items[0] = newPath(edit, shift-vectorNodeShift, node)
 	return ret
 }
 
-/*func (v *Vector) AsTransient() *TransientVector {
-	ret := &TransientVector{Vector{
-		v.count,
-		v.shift,
-		vectorNode{make([]interface{}, len(v.node.items))},
-		make([]interface{}, len(v.node.items)),
-	}}
-	copy(ret.root.items, v.node.items)
-	copy(ret.tail, v.tail)
-	return retn
+// Makes a TransientVector with the contents of the vector. The vector itself is
+// not affected by what is done with the transient.
+func (v *Vector) AsTransient() *TransientVector {
+	edit := &vectorEdit{true}
+	root := vectorNode{edit: edit, items: make([]interface{}, len(v.root.items))}
+	copy(root.items, v.root.items)
+	tail := make([]interface{}, vectorNodeLen)
+	copy(tail, v.tail)
+	return &TransientVector{v.count, v.shift, root, tail}
 }
 
+// A TransientVector is a mutable version of a Vector, meant for building vectors
+// in batch without making an intermediate value on every step. Operations on it
+// modify it in place, reusing the nodes it owns; it still shares the rest with
+// the vector it was made from, which is never changed.
+// A TransientVector must not be used after calling Persistent on it, nor from
+// several goroutines at once.
 type TransientVector struct {
-	Vector
+	count int
+	shift uint
+	root  vectorNode
+	tail  []interface{}
+}
+
+// Makes a persistent Vector from the transient, which can't be used anymore.
+func (t *TransientVector) Persistent() *Vector {
+	t.ensureEditable()
+	t.root.edit.active = false
+	trimmedTail := make([]interface{}, t.count-t.tailoff())
+	copy(trimmedTail, t.tail)
+	return &Vector{t.count, t.shift, t.root, trimmedTail, nil}
+}
+
+// Gives the number of elements in the transient.
+func (t *TransientVector) Count() int {
+	t.ensureEditable()
+	return t.count
+}
+
+// Gives the i-th element in the transient. It will panic if i >= t.Count().
+func (t *TransientVector) Nth(i int) interface{} {
+	t.ensureEditable()
+	subsl := t.arrayFor(i)
+	return subsl[i&(vectorNodeLen-1)]
+}
+
+// Appends x at the end of the transient.
+func (t *TransientVector) Conj(x interface{}) *TransientVector {
+	t.ensureEditable()
+	if t.count-t.tailoff() < vectorNodeLen {
+		t.tail[t.count&(vectorNodeLen-1)] = x
+		t.count++
+		return t
+	}
+	tailNode := vectorNode{edit: t.root.edit, items: t.tail}
+	t.tail = make([]interface{}, vectorNodeLen)
+	t.tail[0] = x
+	if (t.count >> vectorNodeShift) > (1 << t.shift) {
+		newRoot := vectorNode{edit: t.root.edit, items: make([]interface{}, vectorNodeLen)}
+		newRoot.items[0] = t.root
+		newRoot.items[1] = newPath(t.root.edit, t.shift, tailNode)
+		t.root = newRoot
+		t.shift += vectorNodeShift
+	} else {
+		t.root = t.pushTail(t.shift, t.root, tailNode)
+	}
+	t.count++
+	return t
+}
+
+// Sets item i of the transient to x. It will panic if i > t.Count().
+func (t *TransientVector) Assoc(i int, x interface{}) *TransientVector {
+	t.ensureEditable()
+	if i < 0 || i > t.count {
+		panic(outOfBounds)
+	}
+	if i == t.count {
+		return t.Conj(x)
+	}
+	if i >= t.tailoff() {
+		t.tail[i&(vectorNodeLen-1)] = x
+		return t
+	}
+	t.root = t.doAssoc(t.shift, t.root, i, x)
+	return t
 }
 
-func (v *TransientVector) Conj(x interface{}) *TransientVector {
-}*/
+// Removes the last element of the transient. It will panic if it is empty.
+func (t *TransientVector) Pop() *TransientVector {
+	t.ensureEditable()
+	if t.count == 0 {
+		panic(popEmpty)
+	}
+	if t.count == 1 {
+		t.count = 0
+		t.tail[0] = nil
+		return t
+	}
+	i := t.count - 1
+	if i&(vectorNodeLen-1) > 0 {
+		t.tail[i&(vectorNodeLen-1)] = nil
+		t.count--
+		return t
+	}
+	newTail := make([]interface{}, vectorNodeLen)
+	copy(newTail, t.arrayFor(t.count-2))
+	newRoot, ok := t.popTail(t.shift, t.root)
+	if !ok {
+		newRoot = vectorNode{edit: t.root.edit, items: make([]interface{}, vectorNodeLen)}
+	}
+	if t.shift > vectorNodeShift && newRoot.items[1] == nil {
+		newRoot = t.ensureEditableNode(newRoot.items[0].(vectorNode))
+		t.shift -= vectorNodeShift
+	}
+	t.root = newRoot
+	t.count--
+	t.tail = newTail
+	return t
+}
+
+func (t *TransientVector) ensureEditable() {
+	if !t.root.edit.active {
+		panic(transientAfterPersistent)
+	}
+}
+
+// Gives node itself if the transient owns it, or an owned copy otherwise.
+func (t *TransientVector) ensureEditableNode(node vectorNode) vectorNode {
+	if node.edit == t.root.edit {
+		return node
+	}
+	ret := vectorNode{edit: t.root.edit, items: make([]interface{}, len(node.items))}
+	copy(ret.items, node.items)
+	return ret
+}
+
+func (t *TransientVector) tailoff() int {
+	if t.count < vectorNodeLen {
+		return 0
+	}
+	return ((t.count - 1) >> vectorNodeShift) << vectorNodeShift
+}
+
+func (t *TransientVector) arrayFor(i int) []interface{} {
+	if i < 0 || i >= t.count {
+		panic(outOfBounds)
+	}
+	if i >= t.tailoff() {
+		return t.tail
+	}
+	n := t.root
+	for level := t.shift; level > 0; level -= vectorNodeShift {
+		n = n.items[(i>>level)&(vectorNodeLen-1)].(vectorNode)
+	}
+	return n.items
+}
+
+func (t *TransientVector) pushTail(shift uint, parent vectorNode, tailNode vectorNode) vectorNode {
+	ret := t.ensureEditableNode(parent)
+	subi := ((t.count - 1) >> shift) & (vectorNodeLen - 1)
+	nodeToInsert := vectorNode{}
+	if shift == vectorNodeShift {
+		nodeToInsert = tailNode
+	} else {
+		child, ok := parent.items[subi].(vectorNode)
+		if ok {
+			nodeToInsert = t.pushTail(shift-vectorNodeShift, child, tailNode)
+		} else {
+			nodeToInsert = newPath(t.root.edit, shift-vectorNodeShift, tailNode)
+		}
+	}
+	ret.items[subi] = nodeToInsert
+	return ret
+}
+
+func (t *TransientVector) doAssoc(shift uint, node vectorNode, i int, x interface{}) vectorNode {
+	ret := t.ensureEditableNode(node)
+	if shift == 0 {
+		ret.items[i&(vectorNodeLen-1)] = x
+	} else {
+		subi := (i >> shift) & (vectorNodeLen - 1)
+		ret.items[subi] = t.doAssoc(shift-vectorNodeShift, node.items[subi].(vectorNode), i, x)
+	}
+	return ret
+}
+
+// Removes the last leaf from the tree under node. The returned bool is false if
+// no node is left.
+func (t *TransientVector) popTail(shift uint, node vectorNode) (vectorNode, bool) {
+	ret := t.ensureEditableNode(node)
+	subi := ((t.count - 2) >> shift) & (vectorNodeLen - 1)
+	if shift > vectorNodeShift {
+		newChild, ok := t.popTail(shift-vectorNodeShift, node.items[subi].(vectorNode))
+		if !ok && subi == 0 {
+			return vectorNode{}, false
+		}
+		if ok {
+			ret.items[subi] = newChild
+		} else {
+			ret.items[subi] = nil
+		}
+		return ret, true
+	} else if subi == 0 {
+		return vectorNode{}, false
+	}
+	ret.items[subi] = nil
+	return ret, true
+}
@@ -0,0 +1,242 @@
+package persistent
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestVectorPop(t *testing.T) {
+	// Past the point in which the trie gets a second level.
+	const n = vectorNodeLen*vectorNodeLen + 2*vectorNodeLen + 1
+	v := NewVector()
+	expected := []interface{}{}
+	for i := 0; i < n; i++ {
+		v = v.Conj(i)
+		expected = append(expected, i)
+	}
+	history := []*Vector{v}
+	// Every pop is checked, so that it crosses the tail/trie boundary and
+	// the trie loses levels on the way.
+	for len(expected) > 0 {
+		v = v.Pop()
+		expected = expected[:len(expected)-1]
+		if err := checkVector(v, expected); err != nil {
+			t.Fatalf("Popped to %d: %s", len(expected), err)
+		}
+		if len(expected) == vectorNodeLen+1 {
+			history = append(history, v)
+		}
+	}
+	if v.shift != vectorNodeShift {
+		t.Errorf("Empty vector expected to have shift %d, has %d.", vectorNodeShift, v.shift)
+	}
+	if r := panicked(func() { v.Pop() }); r != popEmpty {
+		t.Errorf("Pop of empty vector expected to panic with %v, got %v.", popEmpty, r)
+	}
+	if v.Peek() != nil {
+		t.Errorf("Peek of empty vector expected to give nil, gave %v.", v.Peek())
+	}
+	// Popping must not affect the vectors popped from.
+	for i, old := range history {
+		if err := checkVector(old, ints(0, old.Count())); err != nil {
+			t.Errorf("Version %d: %s", i, err)
+		}
+	}
+	if v = history[1].Conj("x"); v.Nth(vectorNodeLen+1) != "x" || history[1].Count() != vectorNodeLen+1 {
+		t.Errorf("Conj after pop expected to append.")
+	}
+}
+
+func TestSubvec(t *testing.T) {
+	const n = 3*vectorNodeLen + 5
+	v := NewVector(ints(0, n)...)
+	cases := []struct {
+		name     string
+		sv       *SubVector
+		expected []interface{}
+	}{
+		{"whole", v.Subvec(0, n), ints(0, n)},
+		{"empty", v.Subvec(10, 10), ints(0, 0)},
+		{"tail", v.Subvec(n-3, n), ints(n-3, n)},
+		{"across tail", v.Subvec(vectorNodeLen-2, n-1), ints(vectorNodeLen-2, n-1)},
+		{"subvec of subvec", v.Subvec(5, 50).Subvec(10, 20), ints(15, 25)},
+		{"subvec of subvec, empty", v.Subvec(5, 50).Subvec(45, 45), ints(0, 0)},
+		{"subvec of subvec of subvec", v.Subvec(1, n).Subvec(1, n-1).Subvec(1, n-2), ints(3, n)},
+		{"pop", v.Subvec(30, 40).Pop().Pop(), ints(30, 38)},
+		{"pop to empty", v.Subvec(30, 31).Pop(), ints(0, 0)},
+		{"conj", v.Subvec(30, 40).Conj("a"), append(ints(30, 40), "a")},
+		{"conj over parent", v.Subvec(n-2, n).Conj("a").Conj("b"), append(ints(n-2, n), "a", "b")},
+		{"assoc", v.Subvec(30, 33).Assoc(1, "a"), []interface{}{30, "a", 32}},
+		{"assoc at end", v.Subvec(30, 33).Assoc(3, "a"), []interface{}{30, 31, 32, "a"}},
+		{"subvec after conj", v.Subvec(30, 33).Conj("a").Subvec(2, 4), []interface{}{32, "a"}},
+	}
+	for _, c := range cases {
+		if err := checkVector(c.sv, c.expected); err != nil {
+			t.Errorf("Case '%s': %s", c.name, err)
+		}
+	}
+	if err := checkVector(v, ints(0, n)); err != nil {
+		t.Errorf("Vector changed by its subvectors: %s", err)
+	}
+
+	bad := []struct {
+		name     string
+		f        func()
+		expected error
+	}{
+		{"negative start", func() { v.Subvec(-1, 3) }, outOfBounds},
+		{"end past count", func() { v.Subvec(0, n+1) }, outOfBounds},
+		{"start after end", func() { v.Subvec(3, 2) }, outOfBounds},
+		{"sub end past count", func() { v.Subvec(3, 6).Subvec(0, 4) }, outOfBounds},
+		{"sub start after end", func() { v.Subvec(3, 6).Subvec(2, 1) }, outOfBounds},
+		{"nth past count", func() { v.Subvec(3, 6).Nth(3) }, outOfBounds},
+		{"nth negative", func() { v.Subvec(3, 6).Nth(-1) }, outOfBounds},
+		{"assoc past count", func() { v.Subvec(3, 6).Assoc(4, 1) }, outOfBounds},
+		{"pop empty", func() { v.Subvec(3, 3).Pop() }, popEmpty},
+	}
+	for _, c := range bad {
+		if r := panicked(c.f); r != c.expected {
+			t.Errorf("Case '%s': expected to panic with %v, got %v.", c.name, c.expected, r)
+		}
+	}
+}
+
+// Gives the ints from start (inclusive) to end (exclusive).
+func ints(start, end int) []interface{} {
+	ret := []interface{}{}
+	for i := start; i < end; i++ {
+		ret = append(ret, i)
+	}
+	return ret
+}
+
+func TestTransientVector(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	// Sizes around the tail/trie boundary and the first levels of the trie.
+	for _, n := range []int{0, 1, 31, 32, 33, 64, 1024, 1056, 1057, 2000} {
+		base := NewVector()
+		for i := 0; i < n/2; i++ {
+			base = base.Conj(-i)
+		}
+		expected := make([]interface{}, 0, n)
+		for i := 0; i < base.Count(); i++ {
+			expected = append(expected, base.Nth(i))
+		}
+		baseItems := append([]interface{}(nil), expected...)
+
+		tv := base.AsTransient()
+		for i := base.Count(); i < n; i++ {
+			tv = tv.Conj(i)
+			expected = append(expected, i)
+		}
+		for j := 0; j < n/3; j++ {
+			i := rnd.Intn(len(expected))
+			tv = tv.Assoc(i, fmt.Sprint(j))
+			expected[i] = fmt.Sprint(j)
+		}
+		if err := checkTransientVector(tv, expected); err != nil {
+			t.Errorf("Size %d after conj and assoc: %s", n, err)
+		}
+		for len(expected) > n/4 {
+			tv = tv.Pop()
+			expected = expected[:len(expected)-1]
+			if err := checkTransientVector(tv, expected); err != nil {
+				t.Fatalf("Size %d popped to %d: %s", n, len(expected), err)
+			}
+		}
+		// Conj after pops reuses the slots the pops left behind.
+		for i := 0; i < 40; i++ {
+			tv = tv.Conj(i)
+			expected = append(expected, i)
+		}
+
+		v := tv.Persistent()
+		if err := checkVector(v, expected); err != nil {
+			t.Errorf("Size %d made persistent: %s", n, err)
+		}
+		if err := checkVector(base, baseItems); err != nil {
+			t.Errorf("Size %d, original vector changed by its transient: %s", n, err)
+		}
+	}
+}
+
+func TestTransientVectorAfterPersistent(t *testing.T) {
+	ops := map[string]func(tv *TransientVector){
+		"Persistent": func(tv *TransientVector) { tv.Persistent() },
+		"Count":      func(tv *TransientVector) { tv.Count() },
+		"Nth":        func(tv *TransientVector) { tv.Nth(0) },
+		"Conj":       func(tv *TransientVector) { tv.Conj(1) },
+		"Assoc":      func(tv *TransientVector) { tv.Assoc(0, 1) },
+		"Pop":        func(tv *TransientVector) { tv.Pop() },
+	}
+	for name, op := range ops {
+		tv := NewVector(1, 2, 3).AsTransient()
+		v := tv.Persistent()
+		if r := panicked(func() { op(tv) }); r != transientAfterPersistent {
+			t.Errorf("Case '%s': expected to panic with %v, got %v.", name, transientAfterPersistent, r)
+		}
+		if err := checkVector(v, []interface{}{1, 2, 3}); err != nil {
+			t.Errorf("Case '%s': %s", name, err)
+		}
+	}
+}
+
+func TestTransientVectorBounds(t *testing.T) {
+	tv := NewVector().AsTransient()
+	if r := panicked(func() { tv.Pop() }); r != popEmpty {
+		t.Errorf("Pop of empty transient expected to panic with %v, got %v.", popEmpty, r)
+	}
+	if r := panicked(func() { tv.Assoc(1, 1) }); r != outOfBounds {
+		t.Errorf("Assoc out of bounds expected to panic with %v, got %v.", outOfBounds, r)
+	}
+	if tv.Assoc(0, "a"); tv.Count() != 1 || tv.Nth(0) != "a" {
+		t.Errorf("Assoc at the end expected to append.")
+	}
+}
+
+// Calls f, giving what it panicked with, if anything.
+func panicked(f func()) (r interface{}) {
+	defer func() {
+		r = recover()
+	}()
+	f()
+	return nil
+}
+
+// Checks that v has exactly the items in expected, through Count, Nth and Peek,
+// describing the first difference found, if any.
+func checkVector(v interface {
+	Count() int
+	Nth(int) interface{}
+	Peek() interface{}
+}, expected []interface{}) error {
+	if v.Count() != len(expected) {
+		return fmt.Errorf("expected count %d, got %d.", len(expected), v.Count())
+	}
+	for i, x := range expected {
+		if got := v.Nth(i); got != x {
+			return fmt.Errorf("expected item %d to be %v, got %v.", i, x, got)
+		}
+	}
+	var last interface{}
+	if len(expected) > 0 {
+		last = expected[len(expected)-1]
+	}
+	if got := v.Peek(); got != last {
+		return fmt.Errorf("expected to peek %v, got %v.", last, got)
+	}
+	return nil
+}
+
+func checkTransientVector(tv *TransientVector, expected []interface{}) error {
+	if tv.Count() != len(expected) {
+		return fmt.Errorf("expected count %d, got %d.", len(expected), tv.Count())
+	}
+	for i, x := range expected {
+		if got := tv.Nth(i); got != x {
+			return fmt.Errorf("expected item %d to be %v, got %v.", i, x, got)
+		}
+	}
+	return nil
+}
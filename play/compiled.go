@@ -1,15 +1,17 @@
 package main
 
 import (
+	fmt "fmt"
 	reflect "reflect"
+	regexp "regexp"
 	persistent "github.com/tcard/gojure/persistent"
 	lang "github.com/tcard/gojure/lang"
-	fmt "fmt"
 )
 
 var _ *persistent.List
 var _ lang.Symbol
 var _ reflect.Type
+var _ *regexp.Regexp
 
 type SymTable struct {
 	parent	*SymTable
@@ -30,35 +32,56 @@ var symbols = &SymTable{m: map[string]interface {
 }{}}
 
 func main() {
-	symbols.m[`false`] = false
-	symbols.m[`apply`] = func(xs ...interface{}) interface{} {
-		if len(xs) != 2 {
-			panic("bad number of arguments to apply.")
+	symbols.m[`>=`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to >=.")
 		}
-		args := []interface{}{}
-		argsL := xs[1].(*persistent.List)
-		for argsL != nil {
-			args = append(args, argsL.First())
-			argsL = argsL.Rest()
+		for i := 1; i < len(xs); i++ {
+			if !(lang.Compare(xs[i-1], xs[i]) >= 0) {
+				return false
+			}
 		}
-		return xs[0].(func(...interface{}) interface{})(args...)
+		return true
 	}
-	symbols.m[`nil`] = nil
-	symbols.m[`-`] = func(xs ...interface{}) interface{} {
-		ret := xs[0].(int)
-		for _, x := range xs[1:] {
-			ret -= x.(int)
+	symbols.m[`hash`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 1 {
+			panic("bad number of arguments to hash.")
 		}
-		return ret
+		return int(lang.Hash(xs[0]))
 	}
-	symbols.m[`println`] = func(xs ...interface{}) interface{} {
-		fmt.Println(xs...)
+	symbols.m[`and`] = func(xs ...interface{}) interface{} {
+		for _, x := range xs {
+			if lang.IsFalse(x) {
+				return x
+			}
+		}
 		return nil
+		return true
 	}
-	symbols.m[`/`] = func(xs ...interface{}) interface{} {
-		ret := xs[0].(int)
+	symbols.m[`deref`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 1 {
+			panic("bad number of arguments to deref.")
+		}
+		return xs[0].(interface{ Deref() interface{} }).Deref()
+	}
+	symbols.m[`not=`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to not=.")
+		}
+		for i := 1; i < len(xs); i++ {
+			if !lang.Equiv(xs[i-1], xs[i]) {
+				return true
+			}
+		}
+		return false
+	}
+	symbols.m[`*'`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			return 1
+		}
+		ret := xs[0]
 		for _, x := range xs[1:] {
-			ret /= x.(int)
+			ret = lang.MultiplyP(ret, x)
 		}
 		return ret
 	}
@@ -70,131 +93,417 @@ func main() {
 		}
 		return nil
 	}
-	symbols.m[`and`] = func(xs ...interface{}) interface{} {
-		for _, x := range xs {
-			if lang.IsFalse(x) {
-				return x
+	symbols.m[`conj`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			return persistent.NewVector()
+		}
+		ret := xs[0]
+		for _, x := range xs[1:] {
+			switch coll := ret.(type) {
+			case *persistent.Vector:
+				ret = coll.Conj(x)
+			case *persistent.SubVector:
+				ret = coll.Conj(x)
+			case *persistent.Queue:
+				ret = coll.Conj(x)
+			case *persistent.HashSet:
+				ret = coll.Conj(x)
+			case *persistent.SortedSet:
+				ret = coll.Conj(x)
+			case *persistent.List:
+				ret = coll.Cons(x)
+			case nil:
+				ret = persistent.NewList(x)
+			default:
+				panic("conj not supported on this type.")
+			}
+		}
+		return ret
+	}
+	symbols.m[`hash-set`] = func(xs ...interface{}) interface{} {
+		return lang.NewHashSet(xs...)
+	}
+	symbols.m[`vector`] = func(xs ...interface{}) interface{} {
+		return persistent.NewVector(xs...)
+	}
+	symbols.m[`re-pattern`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 1 {
+			panic("bad number of arguments to re-pattern.")
+		}
+		return lang.RePattern(xs[0])
+	}
+	symbols.m[`re-matcher`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 2 {
+			panic("bad number of arguments to re-matcher.")
+		}
+		return lang.NewMatcher(xs[0].(*regexp.Regexp), xs[1].(string))
+	}
+	symbols.m[`<`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to <.")
+		}
+		for i := 1; i < len(xs); i++ {
+			if !(lang.Compare(xs[i-1], xs[i]) < 0) {
+				return false
 			}
 		}
-		return nil
 		return true
 	}
+	symbols.m[`pop`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 1 {
+			panic("bad number of arguments to pop.")
+		}
+		switch coll := xs[0].(type) {
+		case *persistent.Vector:
+			return coll.Pop()
+		case *persistent.SubVector:
+			return coll.Pop()
+		case *persistent.Queue:
+			return coll.Pop()
+		case *persistent.List:
+			if coll == nil {
+				panic("can't pop empty list.")
+			}
+			return coll.Rest()
+		case nil:
+			return nil
+		}
+		panic("pop not supported on this type.")
+	}
+	symbols.m[`re-groups`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 1 {
+			panic("bad number of arguments to re-groups.")
+		}
+		return lang.ReGroups(xs[0].(*lang.Matcher))
+	}
+	symbols.m[`/`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to /.")
+		}
+		if len(xs) == 1 {
+			return lang.Divide(1, xs[0])
+		}
+		ret := xs[0]
+		for _, x := range xs[1:] {
+			ret = lang.Divide(ret, x)
+		}
+		return ret
+	}
 	symbols.m[`true`] = true
-	symbols.m[`=`] = func(xs ...interface{}) interface{} {
-		ret := xs[0].(int)
+	symbols.m[`re-matches`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 2 {
+			panic("bad number of arguments to re-matches.")
+		}
+		return lang.ReMatches(xs[0].(*regexp.Regexp), xs[1].(string))
+	}
+	symbols.m[`list`] = func(xs ...interface{}) interface{} {
+		return persistent.NewList(xs...)
+	}
+	symbols.m[`hash-map`] = func(xs ...interface{}) interface{} {
+		if len(xs)%2 != 0 {
+			panic("bad number of arguments to hash-map.")
+		}
+		return lang.NewHashMap(xs...)
+	}
+	symbols.m[`>`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to >.")
+		}
+		for i := 1; i < len(xs); i++ {
+			if !(lang.Compare(xs[i-1], xs[i]) > 0) {
+				return false
+			}
+		}
+		return true
+	}
+	symbols.m[`re-find`] = func(xs ...interface{}) interface{} {
+		switch len(xs) {
+		case 1:
+			m := xs[0].(*lang.Matcher)
+			if !m.Find() {
+				return nil
+			}
+			return m.Groups()
+		case 2:
+			return lang.ReFind(xs[0].(*regexp.Regexp), xs[1].(string))
+		}
+		panic("bad number of arguments to re-find.")
+	}
+	symbols.m[`-`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to -.")
+		}
+		if len(xs) == 1 {
+			return lang.Subtract(0, xs[0])
+		}
+		ret := xs[0]
 		for _, x := range xs[1:] {
-			if x.(int) != ret {
+			ret = lang.Subtract(ret, x)
+		}
+		return ret
+	}
+	symbols.m[`=`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to =.")
+		}
+		for i := 1; i < len(xs); i++ {
+			if !lang.Equiv(xs[i-1], xs[i]) {
 				return false
 			}
 		}
 		return true
 	}
+	symbols.m[`println`] = func(xs ...interface{}) interface{} {
+		fmt.Println(xs...)
+		return nil
+	}
+	symbols.m[`<=`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to <=.")
+		}
+		for i := 1; i < len(xs); i++ {
+			if !(lang.Compare(xs[i-1], xs[i]) <= 0) {
+				return false
+			}
+		}
+		return true
+	}
+	symbols.m[`concat`] = func(xs ...interface{}) interface{} {
+		items := []interface{}{}
+		for _, x := range xs {
+			items = append(items, lang.Items(x)...)
+		}
+		return persistent.NewList(items...)
+	}
+	symbols.m[`nil`] = nil
+	symbols.m[`apply`] = func(xs ...interface{}) interface{} {
+		if len(xs) < 2 {
+			panic("bad number of arguments to apply.")
+		}
+		args := append([]interface{}{}, xs[1:len(xs)-1]...)
+		args = append(args, lang.Items(xs[len(xs)-1])...)
+		return xs[0].(func(...interface{}) interface{})(args...)
+	}
+	symbols.m[`*`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			return 1
+		}
+		ret := xs[0]
+		for _, x := range xs[1:] {
+			ret = lang.Multiply(ret, x)
+		}
+		return ret
+	}
+	symbols.m[`seq`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 1 {
+			panic("bad number of arguments to seq.")
+		}
+		items := lang.Items(xs[0])
+		if len(items) == 0 {
+			return nil
+		}
+		return persistent.NewList(items...)
+	}
+	symbols.m[`count`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 1 {
+			panic("bad number of arguments to count.")
+		}
+		switch coll := xs[0].(type) {
+		case *persistent.List:
+			n := 0
+			for ; coll != nil; coll = coll.Rest() {
+				n++
+			}
+			return n
+		case interface{ Count() int }:
+			return coll.Count()
+		case string:
+			return len([]rune(coll))
+		case nil:
+			return 0
+		}
+		panic("count not supported on this type.")
+	}
+	symbols.m[`peek`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 1 {
+			panic("bad number of arguments to peek.")
+		}
+		switch coll := xs[0].(type) {
+		case *persistent.Vector:
+			return coll.Peek()
+		case *persistent.SubVector:
+			return coll.Peek()
+		case *persistent.Queue:
+			return coll.Peek()
+		case *persistent.List:
+			if coll == nil {
+				return nil
+			}
+			return coll.First()
+		case nil:
+			return nil
+		}
+		panic("peek not supported on this type.")
+	}
+	symbols.m[`-'`] = func(xs ...interface{}) interface{} {
+		if len(xs) == 0 {
+			panic("bad number of arguments to -'.")
+		}
+		if len(xs) == 1 {
+			return lang.SubtractP(0, xs[0])
+		}
+		ret := xs[0]
+		for _, x := range xs[1:] {
+			ret = lang.SubtractP(ret, x)
+		}
+		return ret
+	}
+	symbols.m[`false`] = false
 	symbols.m[`+`] = func(xs ...interface{}) interface{} {
 		if len(xs) == 0 {
 			return 0
 		}
-		ret := xs[0].(int)
+		ret := xs[0]
 		for _, x := range xs[1:] {
-			ret += x.(int)
+			ret = lang.Add(ret, x)
 		}
 		return ret
 	}
-	symbols.m[`*`] = func(xs ...interface{}) interface{} {
+	symbols.m[`+'`] = func(xs ...interface{}) interface{} {
 		if len(xs) == 0 {
-			return 1
+			return 0
 		}
-		ret := xs[0].(int)
+		ret := xs[0]
 		for _, x := range xs[1:] {
-			ret *= x.(int)
+			ret = lang.AddP(ret, x)
 		}
 		return ret
 	}
+	symbols.m[`re-seq`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 2 {
+			panic("bad number of arguments to re-seq.")
+		}
+		if l := lang.ReSeq(xs[0].(*regexp.Regexp), xs[1].(string)); l != nil {
+			return l
+		}
+		return nil
+	}
+	symbols.m[`subvec`] = func(xs ...interface{}) interface{} {
+		if len(xs) != 2 && len(xs) != 3 {
+			panic("bad number of arguments to subvec.")
+		}
+		start := xs[1].(int)
+		switch v := xs[0].(type) {
+		case *persistent.Vector:
+			end := v.Count()
+			if len(xs) == 3 {
+				end = xs[2].(int)
+			}
+			return v.Subvec(start, end)
+		case *persistent.SubVector:
+			end := v.Count()
+			if len(xs) == 3 {
+				end = xs[2].(int)
+			}
+			return v.Subvec(start, end)
+		}
+		panic("subvec not supported on this type.")
+	}
 	var _ = interface{}(nil)
 	var _ = lang.GetImport(fmt.Println).(func(xs ...interface{}) interface{})("holas")
 	var _ = lang.GetImport(fmt.Println).(func(xs ...interface{}) interface{})("holas")
-	var _ = lang.GetImport(fmt.Println).(func(xs ...interface{}) interface{})(persistent.NewList(lang.Symbol{"", "a"}, lang.Symbol{"", "b"}, lang.Symbol{"", "c"}))
+	var _ = lang.GetImport(fmt.Println).(func(xs ...interface{}) interface{})(persistent.NewList(lang.Symbol{NS: "", Name: "a"}, lang.Symbol{NS: "", Name: "b"}, lang.Symbol{NS: "", Name: "c"}))
 	var _ = func(xs ...interface{}) interface{} {
 		symbols.m[`fact`] = interface{}(func(xs ...interface{}) interface{} {
-			symbols := &SymTable{parent: symbols, m: map[string]interface {
-			}{}}
-			symbols.m[`n`] = xs[0]
-			return func() interface{} {
-				var ifRet interface{}
-				ifCond := interface{}(symbols.Get(`=`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 0))
-				if lang.IsFalse(ifCond) {
-					ifRet = symbols.Get(`*`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), symbols.Get(`fact`).(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 1)))
+			switch {
+			case len(xs) == 1:
+				var n__2 interface{} = xs[0]
+				_ = n__2
+				if lang.IsFalse(symbols.Get(`=`).(func(xs ...interface{}) interface{})(n__2, 0)) {
+					return symbols.Get(`*`).(func(xs ...interface{}) interface{})(n__2, symbols.Get(`fact`).(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(n__2, 1)))
 				} else {
-					ifRet = 1
+					return 1
 				}
-				return ifRet
-			}()
+			}
+			panic(&lang.ArityException{Actual: len(xs), Name: "fn"})
 		})
 		return nil
 	}()
 	var _ = symbols.Get(`println`).(func(xs ...interface{}) interface{})(symbols.Get(`fact`).(func(xs ...interface{}) interface{})(6))
 	var _ = func(xs ...interface{}) interface{} {
 		symbols.m[`fibo`] = interface{}(func(xs ...interface{}) interface{} {
-			symbols := &SymTable{parent: symbols, m: map[string]interface {
-			}{}}
-			symbols.m[`n`] = xs[0]
-			return func() interface{} {
-				var ifRet interface{}
-				ifCond := interface{}(symbols.Get(`or`).(func(xs ...interface{}) interface{})(symbols.Get(`=`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 0), symbols.Get(`=`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 1)))
-				if lang.IsFalse(ifCond) {
-					ifRet = symbols.Get(`+`).(func(xs ...interface{}) interface{})(symbols.Get(`fibo`).(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 1)), symbols.Get(`fibo`).(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 2)))
+			switch {
+			case len(xs) == 1:
+				var n__4 interface{} = xs[0]
+				_ = n__4
+				if lang.IsFalse(symbols.Get(`or`).(func(xs ...interface{}) interface{})(symbols.Get(`=`).(func(xs ...interface{}) interface{})(n__4, 0), symbols.Get(`=`).(func(xs ...interface{}) interface{})(n__4, 1))) {
+					return symbols.Get(`+`).(func(xs ...interface{}) interface{})(symbols.Get(`fibo`).(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(n__4, 1)), symbols.Get(`fibo`).(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(n__4, 2)))
 				} else {
-					ifRet = 1
+					return 1
 				}
-				return ifRet
-			}()
+			}
+			panic(&lang.ArityException{Actual: len(xs), Name: "fn"})
 		})
 		return nil
 	}()
 	var _ = symbols.Get(`println`).(func(xs ...interface{}) interface{})(symbols.Get(`fibo`).(func(xs ...interface{}) interface{})(6))
 	var _ = func(xs ...interface{}) interface{} {
 		symbols.m[`Y`] = interface{}(func(xs ...interface{}) interface{} {
-			symbols := &SymTable{parent: symbols, m: map[string]interface {
-			}{}}
-			symbols.m[`f`] = xs[0]
-			return interface{}(func(xs ...interface{}) interface{} {
-				symbols := &SymTable{parent: symbols, m: map[string]interface {
-				}{}}
-				symbols.m[`x`] = xs[0]
-				return symbols.Get(`x`).(func(xs ...interface{}) interface{})(symbols.Get(`x`))
-			}).(func(xs ...interface{}) interface{})(interface{}(func(xs ...interface{}) interface{} {
-				symbols := &SymTable{parent: symbols, m: map[string]interface {
-				}{}}
-				symbols.m[`g`] = xs[0]
-				return symbols.Get(`f`).(func(xs ...interface{}) interface{})(interface{}(func(xs ...interface{}) interface{} {
-					symbols := &SymTable{parent: symbols, m: map[string]interface {
-					}{}}
-					symbols.m[`arg`] = xs[0]
-					return symbols.Get(`g`).(func(xs ...interface{}) interface{})(symbols.Get(`g`)).(func(xs ...interface{}) interface{})(symbols.Get(`arg`))
+			switch {
+			case len(xs) == 1:
+				var f__6 interface{} = xs[0]
+				_ = f__6
+				return interface{}(func(xs ...interface{}) interface{} {
+					switch {
+					case len(xs) == 1:
+						var x__8 interface{} = xs[0]
+						_ = x__8
+						return x__8.(func(xs ...interface{}) interface{})(x__8)
+					}
+					panic(&lang.ArityException{Actual: len(xs), Name: "fn"})
+				}).(func(xs ...interface{}) interface{})(interface{}(func(xs ...interface{}) interface{} {
+					switch {
+					case len(xs) == 1:
+						var g__10 interface{} = xs[0]
+						_ = g__10
+						return f__6.(func(xs ...interface{}) interface{})(interface{}(func(xs ...interface{}) interface{} {
+							switch {
+							case len(xs) == 1:
+								var arg__12 interface{} = xs[0]
+								_ = arg__12
+								return g__10.(func(xs ...interface{}) interface{})(g__10).(func(xs ...interface{}) interface{})(arg__12)
+							}
+							panic(&lang.ArityException{Actual: len(xs), Name: "fn"})
+						}))
+					}
+					panic(&lang.ArityException{Actual: len(xs), Name: "fn"})
 				}))
-			}))
+			}
+			panic(&lang.ArityException{Actual: len(xs), Name: "fn"})
 		})
 		return nil
 	}()
 	var _ = func(xs ...interface{}) interface{} {
 		symbols.m[`fiboY`] = interface{}(func(xs ...interface{}) interface{} {
-			symbols := &SymTable{parent: symbols, m: map[string]interface {
-			}{}}
-			symbols.m[`f`] = xs[0]
-			return interface{}(func(xs ...interface{}) interface{} {
-				symbols := &SymTable{parent: symbols, m: map[string]interface {
-				}{}}
-				symbols.m[`n`] = xs[0]
-				return func() interface{} {
-					var ifRet interface{}
-					ifCond := interface{}(symbols.Get(`or`).(func(xs ...interface{}) interface{})(symbols.Get(`=`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 0), symbols.Get(`=`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 1)))
-					if lang.IsFalse(ifCond) {
-						ifRet = symbols.Get(`+`).(func(xs ...interface{}) interface{})(symbols.Get(`f`).(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 1)), symbols.Get(`f`).(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(symbols.Get(`n`), 2)))
-					} else {
-						ifRet = 1
+			switch {
+			case len(xs) == 1:
+				var f__14 interface{} = xs[0]
+				_ = f__14
+				return interface{}(func(xs ...interface{}) interface{} {
+					switch {
+					case len(xs) == 1:
+						var n__16 interface{} = xs[0]
+						_ = n__16
+						if lang.IsFalse(symbols.Get(`or`).(func(xs ...interface{}) interface{})(symbols.Get(`=`).(func(xs ...interface{}) interface{})(n__16, 0), symbols.Get(`=`).(func(xs ...interface{}) interface{})(n__16, 1))) {
+							return symbols.Get(`+`).(func(xs ...interface{}) interface{})(f__14.(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(n__16, 1)), f__14.(func(xs ...interface{}) interface{})(symbols.Get(`-`).(func(xs ...interface{}) interface{})(n__16, 2)))
+						} else {
+							return 1
+						}
 					}
-					return ifRet
-				}()
-			})
+					panic(&lang.ArityException{Actual: len(xs), Name: "fn"})
+				})
+			}
+			panic(&lang.ArityException{Actual: len(xs), Name: "fn"})
 		})
 		return nil
 	}()
@@ -0,0 +1,129 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tcard/gojure/lang"
+	"github.com/tcard/gojure/persistent"
+)
+
+// A ReaderConditional is a reader conditional, as in #?(:gojure a :clj b), read
+// as data when GojureReader.PreserveConditionals is set.
+type ReaderConditional struct {
+	// Form is the list of features and forms.
+	Form *persistent.List
+	// Splicing tells whether it was a splicing reader conditional, as in #?@(...).
+	Splicing bool
+}
+
+func (rc *ReaderConditional) String() string {
+	if rc.Splicing {
+		return "#?@" + fmt.Sprint(rc.Form)
+	}
+	return "#?" + fmt.Sprint(rc.Form)
+}
+
+// A TaggedLiteral is a tagged literal, as in #my/tag form, read as data when
+// GojureReader.PreserveConditionals is set, or in the forms a reader conditional
+// doesn't choose.
+type TaggedLiteral struct {
+	Tag  lang.Symbol
+	Form interface{}
+}
+
+func (tl *TaggedLiteral) String() string {
+	return "#" + tl.Tag.String() + " " + fmt.Sprint(tl.Form)
+}
+
+var (
+	gojureFeature  = lang.Keyword("gojure")
+	defaultFeature = lang.Keyword("default")
+)
+
+// The items of the form chosen by a splicing reader conditional, to be spliced
+// into the collection being read.
+type spliced []interface{}
+
+// Reads a reader conditional, after the #?. It gives the form for the first
+// feature r has, or noForm if there is none. For splicing ones, the form is given
+// as spliced.
+func (r GojureReader) readConditional() (interface{}, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	splicing := c == '@'
+	if !splicing {
+		r.UnreadByte()
+	}
+	c, err = r.skipSpace()
+	if err != nil {
+		return nil, err
+	}
+	if c != '(' {
+		return nil, errors.New("read-cond body must be a list.")
+	}
+
+	if r.PreserveConditionals {
+		items, err := r.readCompound(')')
+		if err != nil {
+			return nil, err
+		}
+		if len(items)%2 != 0 {
+			return nil, errors.New("read-cond requires an even number of forms.")
+		}
+		return &ReaderConditional{Form: persistent.NewList(items...), Splicing: splicing}, nil
+	}
+
+	var chosen interface{} = noForm
+	for {
+		c, err := r.skipSpace()
+		if err != nil {
+			return nil, err
+		}
+		if c == ')' {
+			break
+		}
+		r.UnreadByte()
+		feature, err := r.Read()
+		if err != nil {
+			return nil, err
+		}
+		kw, ok := feature.(lang.Keyword)
+		if !ok {
+			return nil, errors.New("feature should be a keyword: " + fmt.Sprint(feature))
+		}
+		if c, err = r.skipSpace(); err != nil {
+			return nil, err
+		} else if c == ')' {
+			return nil, errors.New("read-cond requires an even number of forms.")
+		}
+		r.UnreadByte()
+		if chosen == noForm && r.hasFeature(kw) {
+			if chosen, err = r.Read(); err != nil {
+				return nil, err
+			}
+		} else {
+			suppressed := r
+			suppressed.suppressRead = true
+			if _, err := suppressed.Read(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !splicing || chosen == noForm {
+		return chosen, nil
+	}
+	switch v := chosen.(type) {
+	case *persistent.List, *persistent.Vector:
+		return spliced(lang.Items(v)), nil
+	}
+	return nil, errors.New("spliced form list in read-cond-splicing must implement sequential.")
+}
+
+// Tells whether the feature is one a reader conditional read by r can choose.
+func (r GojureReader) hasFeature(kw lang.Keyword) bool {
+	return kw == gojureFeature || kw == defaultFeature || r.Features[kw]
+}
@@ -0,0 +1,101 @@
+package reader
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/tcard/gojure/lang"
+	"github.com/tcard/gojure/persistent"
+)
+
+var (
+	fnSym   = lang.Symbol{Name: "fn*"}
+	restSym = lang.Symbol{Name: "&"}
+)
+
+// Reads an anonymous function literal, as in #(+ % %2), after the #(, and gives
+// the fn* form it stands for: (fn* [p1__1 p2__2] (+ p1__1 p2__2)). Its parameters
+// are the % args in its body: % or %1 for the first one, %n for the n-th one and
+// %& for the rest of them. Parameters up to the greatest %n are taken, even if
+// some aren't used.
+func (r GojureReader) readFn() (interface{}, error) {
+	if r.fnArgs != nil {
+		return nil, errors.New("nested #()s are not allowed.")
+	}
+	start := r.pos.prev
+	r.fnArgs = map[int]lang.Symbol{}
+	items, err := r.readCompound(')')
+	if err != nil {
+		return nil, err
+	}
+	body := persistent.NewList(items...)
+	if body != nil {
+		body = body.WithMeta(posMeta(start))
+	}
+	max := 0
+	for n := range r.fnArgs {
+		if n > max {
+			max = n
+		}
+	}
+	params := []interface{}{}
+	for n := 1; n <= max; n++ {
+		param, ok := r.fnArgs[n]
+		if !ok {
+			param = argSym(n)
+		}
+		params = append(params, param)
+	}
+	if rest, ok := r.fnArgs[-1]; ok {
+		params = append(params, restSym, rest)
+	}
+	return persistent.NewList(fnSym, persistent.NewVector(params...), body), nil
+}
+
+// Reads a % arg, after the %. Outside #(...), it is read as a symbol.
+func (r GojureReader) readArg() (interface{}, error) {
+	bys := []byte{}
+	c, err := r.ReadByte()
+	for err == nil && symbolChar(c) {
+		bys = append(bys, c)
+		c, err = r.ReadByte()
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	} else if err == nil {
+		r.UnreadByte()
+	}
+	token := string(bys)
+	if r.fnArgs == nil {
+		return lang.Symbol{Name: "%" + token}, nil
+	}
+	n := 1
+	switch token {
+	case "":
+	case "&":
+		n = -1
+	default:
+		n, err = strconv.Atoi(token)
+		if err != nil || n < 1 {
+			return nil, errors.New("arg literal must be %, %& or %n, with n a positive integer.")
+		}
+	}
+	sym, ok := r.fnArgs[n]
+	if !ok {
+		sym = argSym(n)
+		r.fnArgs[n] = sym
+	}
+	return sym, nil
+}
+
+// Makes a unique symbol for the n-th parameter of a #(...), or the rest of them if
+// n is -1.
+func argSym(n int) lang.Symbol {
+	id := strconv.FormatInt(atomic.AddInt64(&gensymID, 1), 10)
+	if n == -1 {
+		return lang.Symbol{Name: "rest__" + id}
+	}
+	return lang.Symbol{Name: "p" + strconv.Itoa(n) + "__" + id}
+}
@@ -0,0 +1,80 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tcard/gojure/lang"
+	"github.com/tcard/gojure/persistent"
+)
+
+var (
+	derefSym = lang.Symbol{NS: CoreNS, Name: "deref"}
+	varSym   = lang.Symbol{Name: "var"}
+	tagKey   = lang.Keyword("tag")
+)
+
+// Reads the form after a reader macro like @ or #', and gives it wrapped in a
+// call to sym.
+func (r GojureReader) readWrapped(sym lang.Symbol) (interface{}, error) {
+	form, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	return persistent.NewList(sym, form), nil
+}
+
+// Reads the metadata after a ^ and the form it applies to, and gives the form
+// with the metadata merged onto the one it already had. The metadata may be a map,
+// a keyword, as in ^:private for {:private true}, or a symbol or string, as in
+// ^String for {:tag String}.
+func (r GojureReader) readMeta() (interface{}, error) {
+	m, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	var meta *persistent.HashMap
+	switch v := m.(type) {
+	case *persistent.HashMap:
+		meta = v.WithMeta(nil)
+	case lang.Keyword:
+		meta = lang.NewHashMap(v, true)
+	case lang.Symbol, string:
+		meta = lang.NewHashMap(tagKey, v)
+	default:
+		return nil, errors.New("metadata must be Symbol, Keyword, String or Map.")
+	}
+
+	form, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	switch v := form.(type) {
+	case lang.Symbol:
+		return v.WithMeta(mergeMeta(v.Meta(), meta)), nil
+	case *persistent.List:
+		if v != nil {
+			return v.WithMeta(mergeMeta(v.Meta(), meta)), nil
+		}
+	case *persistent.Vector:
+		return v.WithMeta(mergeMeta(v.Meta(), meta)), nil
+	case *persistent.HashMap:
+		return v.WithMeta(mergeMeta(v.Meta(), meta)), nil
+	case *persistent.HashSet:
+		return v.WithMeta(mergeMeta(v.Meta(), meta)), nil
+	case *persistent.Queue:
+		return v.WithMeta(mergeMeta(v.Meta(), meta)), nil
+	}
+	return nil, errors.New("metadata can only be applied to collections and symbols, not " + fmt.Sprint(form) + ".")
+}
+
+// Gives the entries of meta assoc'ed onto those of base, which may be nil.
+func mergeMeta(base, meta *persistent.HashMap) *persistent.HashMap {
+	if base == nil {
+		return meta
+	}
+	for it := meta.Iterator(); it.Next(); {
+		base = base.Assoc(it.Key(), it.Val())
+	}
+	return base
+}
@@ -0,0 +1,107 @@
+package reader
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Pos is a position in the source text. Offset counts bytes from the start,
+// from 0. Line and Column count from 1; columns count characters, not bytes.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// An Error is an error found while reading, at some position of the source.
+type Error struct {
+	// File is the name of the file being read, if known.
+	File   string
+	Line   int
+	Column int
+	// Snippet is the text of the line where the error happened, up to and
+	// including the character at Column.
+	Snippet string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	s := fmt.Sprintf("%d:%d: %v", e.Line, e.Column, e.Err)
+	if e.File != "" {
+		s = e.File + ":" + s
+	}
+	if e.Snippet != "" {
+		s += "\n\t" + e.Snippet + "\n\t" + strings.Repeat(" ", utf8.RuneCountInString(e.Snippet)-1) + "^"
+	}
+	return s
+}
+
+// A position keeps track of where a GojureReader is, and where it was before
+// reading the last byte or rune so that it can be unread. It also keeps the
+// text of the current line read so far, for error snippets.
+type position struct {
+	cur, prev      Pos
+	line, prevLine []byte
+}
+
+func newPosition() *position {
+	return &position{cur: Pos{Line: 1, Column: 1}}
+}
+
+// Advances past a byte read on its own.
+func (p *position) advanceByte(b byte) {
+	p.prev, p.prevLine = p.cur, p.line
+	p.cur.Offset++
+	switch {
+	case b == '\n':
+		p.newLine()
+	case utf8.RuneStart(b):
+		p.cur.Column++
+		fallthrough
+	default:
+		// Continuation bytes belong to a character already counted.
+		p.line = append(p.line, b)
+	}
+}
+
+// Advances past a rune read from size bytes.
+func (p *position) advanceRune(c rune, size int) {
+	p.prev, p.prevLine = p.cur, p.line
+	p.cur.Offset += size
+	if c == '\n' {
+		p.newLine()
+		return
+	}
+	p.cur.Column++
+	p.line = utf8.AppendRune(p.line, c)
+}
+
+func (p *position) newLine() {
+	p.cur.Line++
+	p.cur.Column = 1
+	p.line = nil
+}
+
+func (p *position) unread() {
+	p.cur, p.line = p.prev, p.prevLine
+}
+
+// Makes an Error for err at the last character read.
+func (p *position) errorAt(file string, err error) *Error {
+	column := p.cur.Column - 1
+	if column < 1 {
+		column = 1
+	}
+	return &Error{
+		File:    file,
+		Line:    p.cur.Line,
+		Column:  column,
+		Snippet: strings.TrimRight(string(p.line), "\r"),
+		Err:     err,
+	}
+}
@@ -4,7 +4,9 @@ package reader
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -20,7 +22,7 @@ func From(source io.Reader) GojureReader {
 	if !ok {
 		bufr = bufio.NewReader(source)
 	}
-	return GojureReader{bufr}
+	return GojureReader{Reader: bufr, NS: "user", pos: newPosition()}
 }
 
 // Returns a GojureReader that reads from a string of text.
@@ -28,53 +30,324 @@ func FromString(s string) GojureReader {
 	return From(strings.NewReader(s))
 }
 
-// A GojureReader is bound to a source of Gojure code in text form.
+// A GojureReader is bound to a source of Gojure code in text form. It keeps track
+// of the position it is at in it, so it must be made with From or FromString.
 type GojureReader struct {
 	*bufio.Reader
+	// NS is the current namespace, against which auto-resolved keywords like
+	// ::foo are resolved.
+	NS string
+	// File is the name of the file being read, if any, as reported in errors.
+	File string
+	// Resolve, if set, namespace-qualifies symbols in syntax-quoted forms which
+	// don't have a namespace. By default, they get the current namespace.
+	Resolve func(sym lang.Symbol) lang.Symbol
+	// DataReaders holds the functions that make the values for tagged literals,
+	// as in #my/tag form, by tag. They're given the form after the tag. They take
+	// precedence over the default ones for #inst, #uuid and #queue. Tags are
+	// matched by namespace and name; their metadata is ignored.
+	DataReaders map[lang.Symbol]func(form interface{}) (interface{}, error)
+	// DefaultDataReader, if set, makes the values for tagged literals whose tag
+	// has no data reader.
+	DefaultDataReader func(tag lang.Symbol, form interface{}) (interface{}, error)
+	// Features holds the features, besides :gojure, for which reader
+	// conditionals like #?(:gojure a :clj b) choose their forms.
+	Features map[lang.Keyword]bool
+	// PreserveConditionals makes reader conditionals be read as
+	// *ReaderConditional, and tagged literals as *TaggedLiteral, instead of
+	// being resolved.
+	PreserveConditionals bool
+	pos                  *position
+	// While reading the body of a #(...), the parameters for the % args found in
+	// it, indexed by position. %& is at index -1.
+	fnArgs map[int]lang.Symbol
+	// Set while reading the forms a reader conditional doesn't choose, whose
+	// tagged literals are then read as *TaggedLiteral, and whose regexps aren't
+	// compiled.
+	suppressRead bool
+}
+
+// Gives the position of the next character to be read.
+func (r GojureReader) Pos() Pos {
+	return r.pos.cur
+}
+
+func (r GojureReader) ReadByte() (byte, error) {
+	c, err := r.Reader.ReadByte()
+	if err == nil {
+		r.pos.advanceByte(c)
+	}
+	return c, err
+}
+
+func (r GojureReader) UnreadByte() error {
+	err := r.Reader.UnreadByte()
+	if err == nil {
+		r.pos.unread()
+	}
+	return err
+}
+
+func (r GojureReader) ReadRune() (rune, int, error) {
+	c, size, err := r.Reader.ReadRune()
+	if err == nil {
+		r.pos.advanceRune(c, size)
+	}
+	return c, size, err
+}
+
+func (r GojureReader) UnreadRune() error {
+	err := r.Reader.UnreadRune()
+	if err == nil {
+		r.pos.unread()
+	}
+	return err
 }
 
 // Reads the next form and gives its reppresentation in core data structures.
 // Gojure lists will be github.com/tcard/gojure/persistent#List. Vectors will be
-// github.com/tcard/gojure/persistent#Vector. Symbols will be
-// github.com/tcard/gojure/lang#Symbol. Strings will be Go strings, and numbers
-// will be Go ints.
+// github.com/tcard/gojure/persistent#Vector. Maps will be
+// github.com/tcard/gojure/persistent#HashMap. Sets will be
+// github.com/tcard/gojure/persistent#HashSet. Queues, written as #queue [...],
+// will be github.com/tcard/gojure/persistent#Queue. Symbols will be
+// github.com/tcard/gojure/lang#Symbol. Keywords will be
+// github.com/tcard/gojure/lang#Keyword. Characters will be
+// github.com/tcard/gojure/lang#Char. Strings will be Go strings. Integers
+// will be Go ints, or *big.Int if they end in N or don't fit in an int; ratios
+// will be *big.Rat, floating-point numbers float64 and decimals ending in M
+// github.com/tcard/gojure/lang#BigDecimal. See
+// github.com/tcard/gojure/lang#ParseNumber for the syntax of numbers.
+//
+// Regexp literals, as in #"a+b", will be *regexp.Regexp.
+//
+// Tagged literals, as in #my/tag form, are read by the function for the tag in
+// r.DataReaders, or else by the default ones: #inst "1985-04-12T23:20:50.52Z"
+// will be a time.Time and #uuid "f81d4fae-7dec-11d0-a765-00a0c91e6bf6" a
+// github.com/tcard/gojure/lang#UUID. Other tags are read by r.DefaultDataReader,
+// if set.
+//
+// Anonymous function literals, as in #(+ % %2), are read as fn* forms.
+//
+// Syntax-quoted forms, as in `(a ~b ~@c), are expanded into the forms that make
+// them, as Clojure does.
+//
+// Reader conditionals, as in #?(:gojure a :clj b :default c), are read as the
+// form for the first of their features that r has, which are :gojure, :default
+// and those in r.Features; if none, nothing is read. Splicing ones, as in
+// #?@(:gojure [a b]), splice the items of the chosen form into the collection
+// they're in.
 //
-// No support for maps, sets, keywords, numbers other than ints, etc. is provided
-// at the moment.
+// Line comments, starting with ; or #!, and forms preceded by #_ are skipped.
 //
-// When the error will be io.EOF.
+// @x is read as (gojure.core/deref x) and #'x as (var x).
+//
+// Lists, vectors and maps have as metadata the :line and :column where they start.
+// Metadata written as ^{:a 1} x, ^:private x or ^String x is merged onto that of
+// the form after it, which must be a collection or a symbol.
+//
+// Errors are given as *Error, with the position where they happened. When there
+// are no more forms to read, the error will be io.EOF.
 func (r GojureReader) Read() (interface{}, error) {
+	form, err := r.read()
+	for err == nil && form == noForm {
+		form, err = r.read()
+	}
+	if _, ok := form.(spliced); ok {
+		form, err = nil, errors.New("reader conditional splicing not allowed at the top level.")
+	}
+	if _, ok := err.(*Error); err != nil && err != io.EOF && !ok {
+		err = r.pos.errorAt(r.File, err)
+	}
+	return form, err
+}
+
+// Read by forms that give nothing, like #_ x. Its type isn't zero-size, as
+// pointers to zero-size values may compare equal to any other.
+var noForm = &noFormT{}
+
+type noFormT struct{ _ byte }
+
+// Reads the next form, which may be noForm.
+func (r GojureReader) read() (interface{}, error) {
 	c, err := r.skipSpace()
 	if err != nil {
 		return nil, err
 	}
+	form, err := r.readStartingWith(c)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return form, err
+}
+
+// Reads a form whose first byte, c, has already been consumed.
+func (r GojureReader) readStartingWith(c byte) (interface{}, error) {
+	start := r.pos.prev
 	switch c {
 	case '(':
 		items, err := r.readCompound(')')
 		if err != nil {
 			return nil, err
 		}
-		return persistent.NewList(items...), nil
+		l := persistent.NewList(items...)
+		if l == nil {
+			return l, nil
+		}
+		return l.WithMeta(posMeta(start)), nil
 	case '[':
 		items, err := r.readCompound(']')
 		if err != nil {
 			return nil, err
 		}
-		return persistent.NewVector(items...), nil
+		return persistent.NewVector(items...).WithMeta(posMeta(start)), nil
+	case '{':
+		items, err := r.readCompound('}')
+		if err != nil {
+			return nil, err
+		}
+		m, err := newMap(items)
+		if err != nil {
+			return nil, err
+		}
+		return m.WithMeta(posMeta(start)), nil
+	case ')', ']', '}':
+		return nil, errors.New("unmatched delimiter: " + string(c))
+	case '#':
+		return r.readDispatch()
+	case '\\':
+		return r.readChar()
+	case '%':
+		return r.readArg()
+	case '`':
+		return r.readSyntaxQuote()
+	case '~':
+		return r.readUnquote()
+	case '@':
+		return r.readWrapped(derefSym)
+	case '^':
+		return r.readMeta()
 	case '\'':
 		quoted, err := r.Read()
 		if err != nil {
 			return nil, err
 		}
-		return persistent.NewList(lang.Symbol{Name: "quote"}, quoted), nil
+		return persistent.NewList(quoteSym, quoted), nil
 	default:
 		r.UnreadByte()
 		return r.readAtom()
 	}
 }
 
+// Reads a form introduced by the dispatch character '#', which has already been
+// consumed.
+func (r GojureReader) readDispatch() (interface{}, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch c {
+	case '{':
+		items, err := r.readCompound('}')
+		if err != nil {
+			return nil, err
+		}
+		return newSet(items)
+	case '(':
+		return r.readFn()
+	case '"':
+		return r.readRegexp()
+	case '?':
+		return r.readConditional()
+	case '\'':
+		return r.readWrapped(varSym)
+	case '^':
+		return r.readMeta()
+	case '_':
+		if _, err := r.Read(); err != nil {
+			return nil, err
+		}
+		return noForm, nil
+	case '!':
+		if err := r.skipLine(); err != nil {
+			return nil, err
+		}
+		return noForm, nil
+	}
+	if symbolChar(c) {
+		tag, err := r.readSymbolPrepending(c)
+		if err != nil {
+			return nil, err
+		}
+		return r.readTagged(tag)
+	}
+	return nil, errors.New("no dispatch macro for '" + string(c) + "'.")
+}
+
+// Reads the form following a tag like #inst, and gives the value the data reader
+// for the tag makes from it.
+func (r GojureReader) readTagged(tag lang.Symbol) (interface{}, error) {
+	form, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if r.PreserveConditionals || r.suppressRead {
+		return &TaggedLiteral{Tag: tag, Form: form}, nil
+	}
+	if f, ok := dataReader(r.DataReaders, tag); ok {
+		return f(form)
+	}
+	if f, ok := dataReader(defaultDataReaders, tag); ok {
+		return f(form)
+	}
+	if r.DefaultDataReader != nil {
+		return r.DefaultDataReader(tag, form)
+	}
+	return nil, errors.New("no reader function for tag " + tag.String() + ".")
+}
+
+// Gives the data reader for tag in readers. Tags are compared by namespace and
+// name, regardless of the metadata either may carry.
+func dataReader(readers map[lang.Symbol]func(interface{}) (interface{}, error), tag lang.Symbol) (func(interface{}) (interface{}, error), bool) {
+	if f, ok := readers[tag.WithMeta(nil)]; ok {
+		return f, true
+	}
+	for k, f := range readers {
+		if lang.Equiv(k, tag) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+var defaultDataReaders = map[lang.Symbol]func(form interface{}) (interface{}, error){
+	{Name: "inst"}: func(form interface{}) (interface{}, error) {
+		s, ok := form.(string)
+		if !ok {
+			return nil, errors.New("#inst expects a string.")
+		}
+		return lang.ParseInst(s)
+	},
+	{Name: "uuid"}: func(form interface{}) (interface{}, error) {
+		s, ok := form.(string)
+		if !ok {
+			return nil, errors.New("#uuid expects a string.")
+		}
+		return lang.ParseUUID(s)
+	},
+	{Name: "queue"}: func(form interface{}) (interface{}, error) {
+		v, ok := form.(*persistent.Vector)
+		if !ok {
+			return nil, errors.New("#queue expects a vector.")
+		}
+		ret := persistent.NewQueue()
+		for i := 0; i < v.Count(); i++ {
+			ret = ret.Conj(v.Nth(i))
+		}
+		return ret, nil
+	},
+}
+
 func (r GojureReader) readAtom() (interface{}, error) {
-	// Just symbols and ints for now.
 	c, err := r.ReadByte()
 	if err != nil {
 		return nil, err
@@ -85,27 +358,22 @@ func (r GojureReader) readAtom() (interface{}, error) {
 		if err != nil && err != io.EOF {
 			return nil, err
 		}
-		r.UnreadByte()
 		if err == io.EOF || !(c >= '0' && c <= '9') {
 			// Symbol '+' or '-'
-			if err == io.EOF {
-				return r.readSymbol()
+			if err == nil {
+				r.UnreadByte()
 			}
 			return r.readSymbolPrepending(sign)
-		} else {
-			ret, err := r.readInt()
-			if sign == '-' {
-				ret = -ret
-			}
-			return ret, err
 		}
+		r.UnreadByte()
+		return r.readNumberPrepending(sign)
 	}
 	r.UnreadByte()
 	switch {
 	case c >= '0' && c <= '9':
-		return r.readInt()
+		return r.readNumberPrepending(0)
 	case c == ':':
-		panic("not yet implemented")
+		return r.readKeyword()
 	case c == '"':
 		return r.readString()
 	}
@@ -126,37 +394,72 @@ func (r GojureReader) readAtom() (interface{}, error) {
 	return ret, err
 }
 
-func (r GojureReader) readInt() (int, error) {
+// Reads a number, prepending sign to it unless it is 0. The number literal
+// extends as a symbol would; it is then parsed with
+// github.com/tcard/gojure/lang#ParseNumber.
+func (r GojureReader) readNumberPrepending(sign byte) (interface{}, error) {
 	bys := []byte{}
-	c, err := r.ReadByte()
-	if err != nil {
-		return 0, err
+	if sign != 0 {
+		bys = append(bys, sign)
 	}
-	if c == '-' || c == '+' {
-		bys = append(bys, c)
-	} else {
-		r.UnreadByte()
-	}
-	c, err = r.ReadByte()
-	for err == nil && unicode.IsDigit(rune(c)) {
+	c, err := r.ReadByte()
+	for err == nil && symbolChar(c) {
 		bys = append(bys, c)
 		c, err = r.ReadByte()
 	}
 	if err != nil && err != io.EOF {
-		return 0, err
+		return nil, err
 	} else if err == nil {
 		r.UnreadByte()
 	}
-	return strconv.Atoi(string(bys))
+	return lang.ParseNumber(string(bys))
+}
+
+func (r GojureReader) readKeyword() (lang.Keyword, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if c != ':' {
+		return "", errors.New("not a keyword.")
+	}
+	autoResolve := false
+	c, err = r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if c == ':' {
+		autoResolve = true
+	} else {
+		r.UnreadByte()
+	}
+	sym, err := r.readSymbol()
+	if err != nil {
+		return "", err
+	}
+	if sym.Name == "" {
+		return "", errors.New("bad keyword, empty name.")
+	}
+	if autoResolve {
+		if sym.NS != "" {
+			return "", errors.New("bad keyword, auto-resolved keyword with namespace.")
+		}
+		sym.NS = r.NS
+	}
+	return lang.Keyword(sym.String()), nil
 }
 
-var strEscapes = map[string]byte{
-	"n": '\n',
-	"t": '\t',
+var strEscapes = map[rune]rune{
+	't':  '\t',
+	'r':  '\r',
+	'n':  '\n',
+	'b':  '\b',
+	'f':  '\f',
+	'\\': '\\',
+	'"':  '"',
 }
 
 func (r GojureReader) readString() (string, error) {
-	bys := []byte{}
 	quo, err := r.ReadByte()
 	if err != nil {
 		return "", err
@@ -164,32 +467,181 @@ func (r GojureReader) readString() (string, error) {
 	if quo != '"' {
 		return "", errors.New("not a string.")
 	}
-	escaping := false
-	c, err := r.ReadByte()
-	for err == nil && (escaping || c != '"') {
-		if !escaping && c == '\\' {
-			escaping = true
-		} else {
-			if escaping {
-				if esc, ok := strEscapes[string(c)]; ok {
-					c = esc
-				}
+	runes := []rune{}
+	c, _, err := r.ReadRune()
+	for err == nil && c != '"' {
+		if c == '\\' {
+			c, err = r.readStringEscape()
+			if err != nil {
+				break
 			}
-			escaping = false
-			bys = append(bys, c)
 		}
-		c, err = r.ReadByte()
+		runes = append(runes, c)
+		c, _, err = r.ReadRune()
 	}
-	if err != nil {
+	if err == io.EOF {
+		return "", errors.New("EOF while reading string.")
+	} else if err != nil {
 		return "", err
 	}
-	return string(bys), nil
+	return string(runes), nil
+}
+
+// Reads a regexp literal, as in #"a+b", after the #". Unlike in strings, escapes
+// are kept as they are for the regexp to interpret them; a backslash only keeps
+// the character after it from ending the literal.
+// While suppressRead is set, the pattern isn't compiled, as the Go regexp syntax
+// may not be that of the dialect the literal is meant for, and nil is given.
+func (r GojureReader) readRegexp() (*regexp.Regexp, error) {
+	runes := []rune{}
+	c, _, err := r.ReadRune()
+	for err == nil && c != '"' {
+		runes = append(runes, c)
+		if c == '\\' {
+			c, _, err = r.ReadRune()
+			if err != nil {
+				break
+			}
+			runes = append(runes, c)
+		}
+		c, _, err = r.ReadRune()
+	}
+	if err == io.EOF {
+		return nil, errors.New("EOF while reading regex.")
+	} else if err != nil {
+		return nil, err
+	}
+	if r.suppressRead {
+		return nil, nil
+	}
+	return regexp.Compile(string(runes))
+}
+
+// Reads an escape sequence in a string, after the backslash.
+func (r GojureReader) readStringEscape() (rune, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if esc, ok := strEscapes[c]; ok {
+		return esc, nil
+	}
+	switch {
+	case c == 'u':
+		digits := make([]rune, 4)
+		for i := range digits {
+			digits[i], _, err = r.ReadRune()
+			if err != nil {
+				return 0, err
+			}
+		}
+		return parseUnicodeChar(string(digits))
+	case c >= '0' && c <= '7':
+		digits := []rune{c}
+		for len(digits) < 3 {
+			c, _, err = r.ReadRune()
+			if err != nil {
+				return 0, err
+			}
+			if c < '0' || c > '7' {
+				r.UnreadRune()
+				break
+			}
+			digits = append(digits, c)
+		}
+		return parseOctalChar(string(digits))
+	}
+	return 0, errors.New("unsupported escape character: \\" + string(c))
+}
+
+var charNames = map[string]lang.Char{
+	"newline":   '\n',
+	"space":     ' ',
+	"tab":       '\t',
+	"backspace": '\b',
+	"formfeed":  '\f',
+	"return":    '\r',
+}
+
+// Reads a character literal, after the backslash. It is made of at least one
+// character, and then all of those that follow it until whitespace or a macro
+// character.
+func (r GojureReader) readChar() (lang.Char, error) {
+	c, _, err := r.ReadRune()
+	if err == io.EOF {
+		return 0, errors.New("EOF while reading character.")
+	} else if err != nil {
+		return 0, err
+	}
+	token := []rune{c}
+	c, _, err = r.ReadRune()
+	for err == nil && !unicode.IsSpace(c) && !terminatingChar(c) {
+		token = append(token, c)
+		c, _, err = r.ReadRune()
+	}
+	if err != nil && err != io.EOF {
+		return 0, err
+	} else if err == nil {
+		r.UnreadRune()
+	}
+	if len(token) == 1 {
+		return lang.Char(token[0]), nil
+	}
+	s := string(token)
+	if ch, ok := charNames[s]; ok {
+		return ch, nil
+	}
+	switch {
+	case token[0] == 'u' && len(token) == 5:
+		ch, err := parseUnicodeChar(string(token[1:]))
+		if err != nil {
+			return 0, err
+		}
+		if ch >= 0xD800 && ch <= 0xDFFF {
+			return 0, errors.New("invalid character constant: \\" + s)
+		}
+		return lang.Char(ch), nil
+	case token[0] == 'o' && len(token) <= 4:
+		ch, err := parseOctalChar(string(token[1:]))
+		if err != nil {
+			return 0, err
+		}
+		return lang.Char(ch), nil
+	}
+	return 0, errors.New("unsupported character: \\" + s)
+}
+
+// Parses the four hexadecimal digits of a \uXXXX escape.
+func parseUnicodeChar(digits string) (rune, error) {
+	n, err := strconv.ParseUint(digits, 16, 16)
+	if err != nil || len(digits) != 4 {
+		return 0, errors.New("invalid unicode escape: \\u" + digits)
+	}
+	return rune(n), nil
+}
+
+// Parses the up to three octal digits of a \oNNN escape, at most \o377.
+func parseOctalChar(digits string) (rune, error) {
+	n, err := strconv.ParseUint(digits, 8, 16)
+	if err != nil || len(digits) == 0 || len(digits) > 3 || n > 0377 {
+		return 0, errors.New("invalid octal escape: \\o" + digits)
+	}
+	return rune(n), nil
+}
+
+// Tells whether c ends a token, as whitespace does.
+func terminatingChar(c rune) bool {
+	switch c {
+	case '"', ';', '@', '^', '`', '~', '(', ')', '[', ']', '{', '}', '\\', ',':
+		return true
+	}
+	return false
 }
 
 func symbolChar(c byte) bool {
 	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
 		c == '*' || c == '+' || c == '!' || c == '-' || c == '_' || c == '?' || c == '/' ||
-		c == '=' || c == '>' || c == '<' || c == '.'
+		c == '=' || c == '>' || c == '<' || c == '.' || c == '&'
 }
 
 func (r GojureReader) readSymbol() (lang.Symbol, error) {
@@ -207,7 +659,8 @@ func (r GojureReader) readSymbolPrepending(c byte) (lang.Symbol, error) {
 	}
 	bys := []byte{}
 	var err error
-	for err == nil && symbolChar(c) {
+	// Quotes and hashes are allowed after the first character, as in +' or x#.
+	for err == nil && (symbolChar(c) || c == '\'' || c == '#') {
 		if c == '/' {
 			if ret.NS != "" {
 				return ret, errors.New("bad symbol, more than one namespace separator.")
@@ -225,6 +678,42 @@ func (r GojureReader) readSymbolPrepending(c byte) (lang.Symbol, error) {
 		r.UnreadByte()
 	}
 	ret.Name = string(bys)
+	if ret.NS == "" && ret.Name == "" {
+		// Just the symbol /.
+		ret.Name = "/"
+	}
+	return ret, nil
+}
+
+// Makes the metadata for a form read at pos.
+func posMeta(pos Pos) *persistent.HashMap {
+	return lang.NewHashMap(lang.Keyword("line"), pos.Line, lang.Keyword("column"), pos.Column)
+}
+
+// Makes a map from the keys and values read in a map literal.
+func newMap(items []interface{}) (*persistent.HashMap, error) {
+	if len(items)%2 != 0 {
+		return nil, errors.New("map literal must contain an even number of forms.")
+	}
+	ret := lang.NewHashMap()
+	for i := 0; i < len(items); i += 2 {
+		if ret.Contains(items[i]) {
+			return nil, errors.New("duplicate key in map literal: " + fmt.Sprint(items[i]))
+		}
+		ret = ret.Assoc(items[i], items[i+1])
+	}
+	return ret, nil
+}
+
+// Makes a set from the items read in a set literal.
+func newSet(items []interface{}) (*persistent.HashSet, error) {
+	ret := lang.NewHashSet()
+	for _, x := range items {
+		if ret.Contains(x) {
+			return nil, errors.New("duplicate key in set literal: " + fmt.Sprint(x))
+		}
+		ret = ret.Conj(x)
+	}
 	return ret, nil
 }
 
@@ -236,22 +725,44 @@ func (r GojureReader) readCompound(delim byte) ([]interface{}, error) {
 	c, err := r.skipSpace()
 	for err == nil && c != delim {
 		r.UnreadByte()
-		next, err = r.Read()
-		if err == nil {
-			ret = append(ret, next)
-		} else {
+		next, err = r.read()
+		if err != nil {
 			return ret, err
 		}
+		if s, ok := next.(spliced); ok {
+			ret = append(ret, s...)
+		} else if next != noForm {
+			ret = append(ret, next)
+		}
 		c, err = r.skipSpace()
 	}
 
 	return ret, err
 }
 
+// Skips whitespace, commas and ; comments, giving the first byte after them.
 func (r GojureReader) skipSpace() (byte, error) {
 	c, err := r.ReadByte()
-	for err == nil && (unicode.IsSpace(rune(c)) || c == ',') {
+	for err == nil && (unicode.IsSpace(rune(c)) || c == ',' || c == ';') {
+		if c == ';' {
+			err = r.skipLine()
+			if err != nil {
+				break
+			}
+		}
 		c, err = r.ReadByte()
 	}
 	return c, err
 }
+
+// Skips everything until the end of the line.
+func (r GojureReader) skipLine() error {
+	c, err := r.ReadByte()
+	for err == nil && c != '\n' {
+		c, err = r.ReadByte()
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -2,15 +2,21 @@ package reader
 
 import (
 	"io"
+	"math"
+	"math/big"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tcard/gojure/lang"
 	"github.com/tcard/gojure/persistent"
 )
 
 func TestEmpty(t *testing.T) {
-	cases := []string{"", " ", "    ", "\t\n \r\n  "}
+	cases := []string{"", " ", "    ", "\t\n \r\n  ", "; comment", " ;; comment\n ; more\n",
+		"#!/usr/bin/env gojure\n", "#_ 1", "#_ #_ (a) [b]", "#_ ; comment\n [1 2]"}
 	for _, s := range cases {
 		r := FromString(s)
 		form, err := r.Read()
@@ -45,6 +51,39 @@ var testCases = map[string]formTypeTest{
 			{false, `  " a  `, nil, 0},
 			{true, `  "\"" `, "\"", len(`  "\""`)},
 			{true, `  "ho \n l\\\"a" `, "ho \n l\\\"a", len(`  "ho \n l\\\"a"`)},
+			{true, `"\t\r\b\f\\"`, "\t\r\b\f\\", len(`"\t\r\b\f\\"`)},
+			{true, `"\u00e9\u00C9 \101\0"`, "éÉ A\x00", len(`"\u00e9\u00C9 \101\0"`)},
+			{true, `"ñandú 日本"`, "ñandú 日本", len(`"ñandú 日本"`)},
+			{false, `"\a"`, nil, 0},
+			{false, `"\u00"`, nil, 0},
+			{false, `"\u00g0"`, nil, 0},
+			{false, `"\400"`, nil, 0},
+			{false, `"a\"`, nil, 0},
+		},
+	},
+	"char": formTypeTest{
+		formType: "char",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(lang.Char)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, ` \a `, lang.Char('a'), len(` \a`)},
+			{true, `\é`, lang.Char('é'), len(`\é`)},
+			{true, `\(`, lang.Char('('), len(`\(`)},
+			{true, `\\`, lang.Char('\\'), len(`\\`)},
+			{true, `\newline`, lang.Char('\n'), len(`\newline`)},
+			{true, `\space`, lang.Char(' '), len(`\space`)},
+			{true, `\tab`, lang.Char('\t'), len(`\tab`)},
+			{true, `\u00e9`, lang.Char('é'), len(`\u00e9`)},
+			{true, `\o101`, lang.Char('A'), len(`\o101`)},
+			{true, `\u`, lang.Char('u'), len(`\u`)},
+			{true, `\o`, lang.Char('o'), len(`\o`)},
+			{false, `\ab`, nil, 0},
+			{false, `\u00e`, nil, 0},
+			{false, `\ud800`, nil, 0},
+			{false, `\o400`, nil, 0},
+			{false, `\`, nil, 0},
 		},
 	},
 	"symbol": formTypeTest{
@@ -71,6 +110,27 @@ var testCases = map[string]formTypeTest{
 			{true, "-", lang.Symbol{Name: "-"}, len("-")},
 			{true, "/-", lang.Symbol{Name: "-", NS: ""}, len("/-")},
 			{true, "ab/-3", lang.Symbol{Name: "-3", NS: "ab"}, len("ab/-3")},
+			{true, "+'", lang.Symbol{Name: "+'"}, len("+'")},
+			{true, " / ", lang.Symbol{Name: "/"}, len(" /")},
+			{true, "a'b'", lang.Symbol{Name: "a'b'"}, len("a'b'")},
+		},
+	},
+	"keyword": formTypeTest{
+		formType: "keyword",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(lang.Keyword)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, " :aa ", lang.Keyword("aa"), len(" :aa")},
+			{true, ":abc/d", lang.Keyword("abc/d"), len(":abc/d")},
+			{true, "  ::d*", lang.Keyword("user/d*"), len("  ::d*")},
+			{true, ":a.b/-c?", lang.Keyword("a.b/-c?"), len(":a.b/-c?")},
+			{false, ":", nil, 0},
+			{false, ": a", nil, 0},
+			{false, ":a/b/c", nil, 0},
+			{false, "::a/b", nil, 0},
+			{false, ":::a", nil, 0},
 		},
 	},
 	"int": formTypeTest{
@@ -92,6 +152,76 @@ var testCases = map[string]formTypeTest{
 			{false, "+", nil, 0},
 			{false, "-", nil, 0},
 			{false, "-/-", nil, 0},
+			{true, "0x2A", 42, len("0x2A")},
+			{true, "-0X2a", -42, len("-0X2a")},
+			{true, "052", 42, len("052")},
+			{true, "2r101010", 42, len("2r101010")},
+			{true, "36rZ", 35, len("36rZ")},
+			{true, "8/4", 2, len("8/4")},
+			{false, "09", nil, 0},
+			{false, "0x", nil, 0},
+			{false, "2r102", nil, 0},
+			{false, "37r1", nil, 0},
+			{false, "1a", nil, 0},
+			{false, "1/0", nil, 0},
+		},
+	},
+	"bigint": formTypeTest{
+		formType: "bigint",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(*big.Int)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, "42N", big.NewInt(42), len("42N")},
+			{true, "-0x2AN", big.NewInt(-42), len("-0x2AN")},
+			{true, "12345678901234567890", bigFromString("12345678901234567890"), len("12345678901234567890")},
+			{true, "-12345678901234567890N", bigFromString("-12345678901234567890"), len("-12345678901234567890N")},
+			{false, "42NN", nil, 0},
+		},
+	},
+	"ratio": formTypeTest{
+		formType: "ratio",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(*big.Rat)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, "1/3", big.NewRat(1, 3), len("1/3")},
+			{true, "-6/4", big.NewRat(-3, 2), len("-6/4")},
+			{false, "1/-3", nil, 0},
+			{false, "1/3/4", nil, 0},
+		},
+	},
+	"float": formTypeTest{
+		formType: "float",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(float64)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, "1.5", 1.5, len("1.5")},
+			{true, "-1.", -1.0, len("-1.")},
+			{true, "15e-1", 1.5, len("15e-1")},
+			{true, "+0.15E+1", 1.5, len("+0.15E+1")},
+			{true, "1e400", math.Inf(1), len("1e400")},
+			{true, "-1e400", math.Inf(-1), len("-1e400")},
+			{false, ".5", nil, 0},
+			{false, "1.5.", nil, 0},
+			{false, "1e", nil, 0},
+		},
+	},
+	"decimal": formTypeTest{
+		formType: "decimal",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(*lang.BigDecimal)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, "1.50M", lang.NewBigDecimal(big.NewInt(150), 2), len("1.50M")},
+			{true, "-15M", lang.NewBigDecimal(big.NewInt(-15), 0), len("-15M")},
+			{true, "15e-1M", lang.NewBigDecimal(big.NewInt(15), 1), len("15e-1M")},
+			{false, "1.5MM", nil, 0},
 		},
 	},
 	"vector": formTypeTest{
@@ -104,6 +234,62 @@ var testCases = map[string]formTypeTest{
 			{true, " [ ] ", persistent.NewVector(), len(" [ ]")},
 			{true, "[]", persistent.NewVector(), len("[]")},
 			{true, "[  1  \n\t 3 ,,,2]", persistent.NewVector(1, 3, 2), len("[  1  \n\t 3 ,,,2]")},
+			{true, "[1 ; 2 ]\n 3]", persistent.NewVector(1, 3), len("[1 ; 2 ]\n 3]")},
+			{true, "[1 #_ 2 3 #_[4]]", persistent.NewVector(1, 3), len("[1 #_ 2 3 #_[4]]")},
+			{true, "[#_ #_ 1 2 3]", persistent.NewVector(3), len("[#_ #_ 1 2 3]")},
+			{true, "#_ 1 [2]", persistent.NewVector(2), len("#_ 1 [2]")},
+			{false, "[1 #_]", nil, 0},
+			{false, "[1 ; 2]", nil, 0},
+		},
+	},
+	"map": formTypeTest{
+		formType: "map",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(*persistent.HashMap)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, " { } ", lang.NewHashMap(), len(" { }")},
+			{true, "{}", lang.NewHashMap(), len("{}")},
+			{true, "{ :a  1, \n\t :b ,,,2}", lang.NewHashMap(lang.Keyword("a"), 1, lang.Keyword("b"), 2), len("{ :a  1, \n\t :b ,,,2}")},
+			{true, "{nil [1] [1] nil}", lang.NewHashMap(nil, persistent.NewVector(1), persistent.NewVector(1), nil), len("{nil [1] [1] nil}")},
+			{false, "{:a}", nil, 0},
+			{false, "{:a 1 :b}", nil, 0},
+			{false, "{:a 1 :a 2}", nil, 0},
+			{false, "{[1] 1 (1) 2}", nil, 0},
+			{false, "{:a 1", nil, 0},
+		},
+	},
+	"set": formTypeTest{
+		formType: "set",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(*persistent.HashSet)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, " #{ } ", lang.NewHashSet(), len(" #{ }")},
+			{true, "#{}", lang.NewHashSet(), len("#{}")},
+			{true, "#{ :a  1, \n\t :b ,,,2}", lang.NewHashSet(lang.Keyword("a"), 1, lang.Keyword("b"), 2), len("#{ :a  1, \n\t :b ,,,2}")},
+			{true, `#{nil "a"}`, lang.NewHashSet(nil, "a"), len(`#{nil "a"}`)},
+			{false, "#{:a 1 :a}", nil, 0},
+			{false, "#{[1 :b] (1 :b)}", nil, 0},
+			{false, "# {:a 1}", nil, 0},
+			{false, "#{:a 1", nil, 0},
+		},
+	},
+	"queue": formTypeTest{
+		formType: "queue",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(*persistent.Queue)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, " #queue [ ] ", persistent.NewQueue(), len(" #queue [ ]")},
+			{true, "#queue[]", persistent.NewQueue(), len("#queue[]")},
+			{true, "#queue [1 :b (c)]", persistent.NewQueue(1, lang.Keyword("b"), persistent.NewList(lang.Symbol{Name: "c"})), len("#queue [1 :b (c)]")},
+			{false, "#queue (1 2)", nil, 0},
+			{false, "#queue", nil, 0},
+			{false, "#nosuchtag [1 2]", nil, 0},
 		},
 	},
 	"list": formTypeTest{
@@ -118,6 +304,41 @@ var testCases = map[string]formTypeTest{
 			{true, "(  1  \n\t 3 ,,,2)", persistent.NewList(1, 3, 2), len("[  1  \n\t 3 ,,,2]")},
 		},
 	},
+	"syntax-quoted": formTypeTest{
+		formType: "syntax-quoted",
+		assertType: func(form interface{}) bool {
+			_, ok := form.(*persistent.List)
+			return ok
+		},
+		cases: []formTypeTestCase{
+			{true, "`a", sqList(quoteSym, lang.Symbol{NS: "user", Name: "a"}), len("`a")},
+			{true, "`b/a", sqList(quoteSym, lang.Symbol{NS: "b", Name: "a"}), len("`b/a")},
+			{true, "`if", sqList(quoteSym, lang.Symbol{Name: "if"}), len("`if")},
+			{true, "`()", sqList(listSym), len("`()")},
+			{true, "`(1 ~a ~@b)", sqList(seqSym, sqList(concatSym,
+				sqList(listSym, 1),
+				sqList(listSym, lang.Symbol{Name: "a"}),
+				lang.Symbol{Name: "b"})), len("`(1 ~a ~@b)")},
+			{true, "`(1 (^:m gojure.core/unquote a) (^:m gojure.core/unquote-splicing b))", sqList(seqSym, sqList(concatSym,
+				sqList(listSym, 1),
+				sqList(listSym, lang.Symbol{Name: "a"}),
+				lang.Symbol{Name: "b"})), len("`(1 (^:m gojure.core/unquote a) (^:m gojure.core/unquote-splicing b))")},
+			{true, "`[:k \\c \"s\" nil]", sqList(applySym, vectorSym, sqList(seqSym, sqList(concatSym,
+				sqList(listSym, lang.Keyword("k")),
+				sqList(listSym, lang.Char('c')),
+				sqList(listSym, "s"),
+				sqList(listSym, nil)))), len("`[:k \\c \"s\" nil]")},
+			{true, "`{a ~b}", sqList(applySym, hashMapSym, sqList(seqSym, sqList(concatSym,
+				sqList(listSym, sqList(quoteSym, lang.Symbol{NS: "user", Name: "a"})),
+				sqList(listSym, lang.Symbol{Name: "b"})))), len("`{a ~b}")},
+			{true, "`#{~@a}", sqList(applySym, hashSetSym, sqList(seqSym, sqList(concatSym,
+				lang.Symbol{Name: "a"}))), len("`#{~@a}")},
+			{true, "~a", sqList(unquoteSym, lang.Symbol{Name: "a"}), len("~a")},
+			{true, "~@a", sqList(unquoteSplicingSym, lang.Symbol{Name: "a"}), len("~@a")},
+			{false, "`~@a", nil, 0},
+			{false, "`", nil, 0},
+		},
+	},
 	"quoted": formTypeTest{
 		formType: "quoted",
 		assertType: func(form interface{}) bool {
@@ -132,6 +353,127 @@ var testCases = map[string]formTypeTest{
 	},
 }
 
+func sqList(items ...interface{}) *persistent.List {
+	return persistent.NewList(items...)
+}
+
+func bigFromString(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+// Gives form without the position metadata the reader attaches, so that it can
+// be compared with reflect.DeepEqual.
+func withoutMeta(form interface{}) interface{} {
+	switch v := form.(type) {
+	case lang.Symbol:
+		return v.WithMeta(nil)
+	case *persistent.List:
+		items := []interface{}{}
+		for ; v != nil; v = v.Rest() {
+			items = append(items, withoutMeta(v.First()))
+		}
+		return persistent.NewList(items...)
+	case *persistent.Vector:
+		items := []interface{}{}
+		for i := 0; i < v.Count(); i++ {
+			items = append(items, withoutMeta(v.Nth(i)))
+		}
+		return persistent.NewVector(items...)
+	case *persistent.HashMap:
+		ret := lang.NewHashMap()
+		for it := v.Iterator(); it.Next(); {
+			ret = ret.Assoc(withoutMeta(it.Key()), withoutMeta(it.Val()))
+		}
+		return ret
+	case *persistent.HashSet:
+		ret := lang.NewHashSet()
+		for it := v.Iterator(); it.Next(); {
+			ret = ret.Conj(withoutMeta(it.Item()))
+		}
+		return ret
+	case *persistent.Queue:
+		ret := persistent.NewQueue()
+		for it := v.Iterator(); it.Next(); {
+			ret = ret.Conj(withoutMeta(it.Item()))
+		}
+		return ret
+	}
+	return form
+}
+
+// Tells whether forms a and b have the same types all the way down and are
+// otherwise deeply equal. Collections are compared item by item, as equal ones
+// may differ in their internals, like a vector's transient edit.
+func sameForm(a, b interface{}) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	switch a := a.(type) {
+	case *persistent.List:
+		b := b.(*persistent.List)
+		for ; a != nil && b != nil; a, b = a.Rest(), b.Rest() {
+			if !sameForm(a.First(), b.First()) {
+				return false
+			}
+		}
+		return a == nil && b == nil
+	case *persistent.Vector:
+		b := b.(*persistent.Vector)
+		if a.Count() != b.Count() {
+			return false
+		}
+		for i := 0; i < a.Count(); i++ {
+			if !sameForm(a.Nth(i), b.Nth(i)) {
+				return false
+			}
+		}
+		return true
+	case *persistent.HashMap:
+		b := b.(*persistent.HashMap)
+		if a.Count() != b.Count() {
+			return false
+		}
+		for it := a.Iterator(); it.Next(); {
+			found := false
+			for bit := b.Iterator(); bit.Next() && !found; {
+				found = sameForm(it.Key(), bit.Key()) && sameForm(it.Val(), bit.Val())
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case *persistent.HashSet:
+		b := b.(*persistent.HashSet)
+		if a.Count() != b.Count() {
+			return false
+		}
+		for it := a.Iterator(); it.Next(); {
+			found := false
+			for bit := b.Iterator(); bit.Next() && !found; {
+				found = sameForm(it.Item(), bit.Item())
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case *persistent.Queue:
+		b := b.(*persistent.Queue)
+		if a.Count() != b.Count() {
+			return false
+		}
+		for ait, bit := a.Iterator(), b.Iterator(); ait.Next() && bit.Next(); {
+			if !sameForm(ait.Item(), bit.Item()) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 func (ftt formTypeTest) testFormType(t *testing.T) {
 	for _, c := range ftt.cases {
 		r := FromString(c.source)
@@ -148,7 +490,7 @@ func (ftt formTypeTest) testFormType(t *testing.T) {
 		if !ftt.assertType(form) {
 			t.Errorf("Case '%s' should give a %s, gave '%v'.", c.source, ftt.formType, form)
 		}
-		if !reflect.DeepEqual(form, c.expected) {
+		if !sameForm(withoutMeta(form), c.expected) {
 			t.Errorf("Case '%s' expected to produce %s '%v', produced '%v' instead.",
 				c.source, ftt.formType, c.expected, form)
 		}
@@ -251,3 +593,288 @@ func TestQuote(t *testing.T) {
 		v.testFormType(t)
 	}
 }
+
+func TestPositions(t *testing.T) {
+	r := FromString("  (a\n\t[b ; c\n   {:c (1)}])")
+	form, err := r.Read()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	l := form.(*persistent.List)
+	v := l.Rest().First().(*persistent.Vector)
+	m := v.Nth(1).(*persistent.HashMap)
+	inner, _ := m.Get(lang.Keyword("c"))
+	cases := []struct {
+		meta         *persistent.HashMap
+		line, column int
+	}{
+		{l.Meta(), 1, 3},
+		{v.Meta(), 2, 2},
+		{m.Meta(), 3, 4},
+		{inner.(*persistent.List).Meta(), 3, 8},
+	}
+	for i, c := range cases {
+		line, _ := c.meta.Get(lang.Keyword("line"))
+		column, _ := c.meta.Get(lang.Keyword("column"))
+		if line != c.line || column != c.column {
+			t.Errorf("Form %d expected at %d:%d, found at %v:%v.", i, c.line, c.column, line, column)
+		}
+	}
+	if pos := r.Pos(); pos != (Pos{Offset: 26, Line: 3, Column: 14}) {
+		t.Errorf("Reader expected to end at 3:14, offset 26; ended at %v, offset %d.", pos, pos.Offset)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		source       string
+		line, column int
+		snippet      string
+	}{
+		{"(a b\n  c}", 2, 4, "  c}"},
+		{"[1 \"ñ\\q\"]", 1, 7, "[1 \"ñ\\q"},
+		{"\n\n  (a 1x)", 3, 7, "  (a 1x"},
+		{"(a\n (b", 2, 3, " (b"},
+	}
+	for _, c := range cases {
+		r := FromString(c.source)
+		r.File = "test.gj"
+		_, err := r.Read()
+		rerr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Case %q expected to give a *Error, gave %v.", c.source, err)
+			continue
+		}
+		if rerr.File != "test.gj" || rerr.Line != c.line || rerr.Column != c.column || rerr.Snippet != c.snippet {
+			t.Errorf("Case %q expected to give an error at %d:%d after %q, gave %s:%d:%d after %q.",
+				c.source, c.line, c.column, c.snippet, rerr.File, rerr.Line, rerr.Column, rerr.Snippet)
+		}
+	}
+}
+
+// Gives the auto-gensyms in form, in order.
+func gensymsIn(form interface{}) []lang.Symbol {
+	ret := []lang.Symbol{}
+	switch v := form.(type) {
+	case lang.Symbol:
+		if strings.HasSuffix(v.Name, "__auto__") {
+			ret = append(ret, v)
+		}
+	case *persistent.List:
+		for ; v != nil; v = v.Rest() {
+			ret = append(ret, gensymsIn(v.First())...)
+		}
+	}
+	return ret
+}
+
+func TestGensyms(t *testing.T) {
+	form, err := FromString("`(a# [a# b#])").Read()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	syms := gensymsIn(form)
+	if len(syms) != 3 || syms[0] != syms[1] || syms[0] == syms[2] ||
+		!strings.HasPrefix(syms[0].Name, "a__") || !strings.HasPrefix(syms[2].Name, "b__") {
+		t.Errorf("Bad auto-gensyms: %v", syms)
+	}
+	other, _ := FromString("`a#").Read()
+	if len(syms) > 0 && gensymsIn(other)[0] == syms[0] {
+		t.Errorf("Auto-gensym repeated across syntax-quotes: %v", syms[0])
+	}
+}
+
+func TestFnLiteral(t *testing.T) {
+	form, err := FromString("#(+ % %3 %1 %&)").Read()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	l := form.(*persistent.List)
+	params := l.Rest().First().(*persistent.Vector)
+	body := l.Rest().Rest().First().(*persistent.List)
+	if l.First() != fnSym || params.Count() != 5 || params.Nth(3) != restSym {
+		t.Fatalf("Bad fn literal: %v", form)
+	}
+	expected := persistent.NewList(lang.Symbol{Name: "+"}, params.Nth(0), params.Nth(2), params.Nth(0), params.Nth(4))
+	if !reflect.DeepEqual(withoutMeta(body), expected) {
+		t.Errorf("Fn literal %v expected to have body %v.", form, expected)
+	}
+	if params.Nth(0) == params.Nth(1) || params.Nth(1) == params.Nth(2) {
+		t.Errorf("Fn literal %v has repeated parameters.", form)
+	}
+
+	for _, s := range []string{"#(a #(b))", "#(%0)", "#(%a)", "#(a"} {
+		if form, err := FromString(s).Read(); err == nil {
+			t.Errorf("Case '%s' should fail, gave %v.", s, form)
+		}
+	}
+
+	form, err = FromString("%&").Read()
+	if form != (lang.Symbol{Name: "%&"}) || err != nil {
+		t.Errorf("%%& outside #() expected to be a symbol, gave %v %v.", form, err)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	cases := []struct {
+		source string
+		meta   *persistent.HashMap
+	}{
+		{"^:private x", lang.NewHashMap(lang.Keyword("private"), true)},
+		{"^String x", lang.NewHashMap(lang.Keyword("tag"), lang.Symbol{Name: "String"})},
+		{`^"String" x`, lang.NewHashMap(lang.Keyword("tag"), "String")},
+		{"^{:a 1} ^:b x", lang.NewHashMap(lang.Keyword("a"), 1, lang.Keyword("b"), true)},
+		{"^:a ^{:a 2} x", lang.NewHashMap(lang.Keyword("a"), true)},
+		{"^:a [1]", lang.NewHashMap(lang.Keyword("a"), true, lang.Keyword("line"), 1, lang.Keyword("column"), 5)},
+		{"^:a #{1}", lang.NewHashMap(lang.Keyword("a"), true)},
+	}
+	for _, c := range cases {
+		form, err := FromString(c.source).Read()
+		if err != nil {
+			t.Errorf("Case '%s': unexpected error: %v", c.source, err)
+			continue
+		}
+		meta := form.(interface {
+			Meta() *persistent.HashMap
+		}).Meta()
+		if !lang.Equiv(meta, c.meta) {
+			t.Errorf("Case '%s' expected to have metadata %v, has %v.", c.source, c.meta, meta)
+		}
+	}
+
+	for _, s := range []string{"^1 x", "^:a 1", "^:a ()", "^:a"} {
+		if form, err := FromString(s).Read(); err == nil {
+			t.Errorf("Case '%s' should fail, gave %v.", s, form)
+		}
+	}
+
+	sym := lang.Symbol{Name: "x"}
+	if form, _ := FromString("^:a x").Read(); !lang.Equiv(form, sym) {
+		t.Errorf("Symbol with metadata %v expected to be equivalent to %v.", form, sym)
+	}
+}
+
+func TestDerefAndVar(t *testing.T) {
+	for source, expected := range map[string]interface{}{
+		"@x":  persistent.NewList(derefSym, lang.Symbol{Name: "x"}),
+		"#'x": persistent.NewList(varSym, lang.Symbol{Name: "x"}),
+	} {
+		form, err := FromString(source).Read()
+		if err != nil || !reflect.DeepEqual(form, expected) {
+			t.Errorf("Case '%s' expected to give %v, gave %v %v.", source, expected, form, err)
+		}
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	for source, expected := range map[string]string{
+		`#"a+b"`:     `a+b`,
+		`#"\d\.\""`:  `\d\.\"`,
+		`#"\\"`:      `\\`,
+		`#"(\w+)=ñ"`: `(\w+)=ñ`,
+	} {
+		form, err := FromString(source).Read()
+		re, ok := form.(*regexp.Regexp)
+		if err != nil || !ok || re.String() != expected {
+			t.Errorf("Case '%s' expected to give regexp %s, gave %v %v.", source, expected, form, err)
+		}
+	}
+
+	for _, s := range []string{`#"a(b"`, `#"ab`, `#"\`} {
+		if form, err := FromString(s).Read(); err == nil {
+			t.Errorf("Case '%s' should fail, gave %v.", s, form)
+		}
+	}
+}
+
+func TestTaggedLiterals(t *testing.T) {
+	cases := map[string]interface{}{
+		`#inst "1985-04-12T23:20:50.52Z"`:      time.Date(1985, 4, 12, 23, 20, 50, 520000000, time.UTC),
+		`#inst "1985-04-12T23:20:50.52-02:30"`: time.Date(1985, 4, 13, 1, 50, 50, 520000000, time.UTC),
+		`#inst "1985"`:                         time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC),
+		`#uuid "f81d4fae-7dec-11d0-A765-00a0c91e6bf6"`: lang.UUID{0xf8, 0x1d, 0x4f, 0xae, 0x7d, 0xec, 0x11, 0xd0,
+			0xa7, 0x65, 0x00, 0xa0, 0xc9, 0x1e, 0x6b, 0xf6},
+	}
+	for source, expected := range cases {
+		form, err := FromString(source).Read()
+		if err != nil {
+			t.Errorf("Case '%s': unexpected error: %v", source, err)
+		} else if tm, ok := form.(time.Time); ok && !tm.Equal(expected.(time.Time)) || !ok && form != expected {
+			t.Errorf("Case '%s' expected to give %v, gave %v.", source, expected, form)
+		}
+	}
+
+	for _, s := range []string{`#inst "1985-13"`, `#inst "1985-02-30"`, `#inst 1985`, `#uuid "f81d4fae7dec11d0a76500a0c91e6bf6"`, `#my/tag 1`} {
+		if form, err := FromString(s).Read(); err == nil {
+			t.Errorf("Case '%s' should fail, gave %v.", s, form)
+		}
+	}
+
+	r := FromString(`#my/tag [1 2] #inst "2000" #my/meta 1 #other/tag x`)
+	r.DataReaders = map[lang.Symbol]func(interface{}) (interface{}, error){
+		{NS: "my", Name: "tag"}: func(form interface{}) (interface{}, error) {
+			return form.(*persistent.Vector).Count(), nil
+		},
+		{Name: "inst"}: func(form interface{}) (interface{}, error) {
+			return "inst " + form.(string), nil
+		},
+		lang.Symbol{NS: "my", Name: "meta"}.WithMeta(lang.NewHashMap(lang.Keyword("a"), 1)): func(form interface{}) (interface{}, error) {
+			return "meta", nil
+		},
+	}
+	r.DefaultDataReader = func(tag lang.Symbol, form interface{}) (interface{}, error) {
+		return persistent.NewVector(tag, form), nil
+	}
+	expected := []interface{}{2, "inst 2000", "meta", persistent.NewVector(lang.Symbol{NS: "other", Name: "tag"}, lang.Symbol{Name: "x"})}
+	for _, e := range expected {
+		form, err := r.Read()
+		if err != nil || !lang.Equiv(form, e) {
+			t.Errorf("Expected %v from data readers, gave %v %v.", e, form, err)
+		}
+	}
+}
+
+func TestConditionals(t *testing.T) {
+	clj := map[lang.Keyword]bool{lang.Keyword("clj"): true}
+	cases := []struct {
+		source   string
+		features map[lang.Keyword]bool
+		expected interface{}
+	}{
+		{"#?(:clj 1 :gojure 2 :default 3)", nil, 2},
+		{"#?(:clj 1 :default 3)", nil, 3},
+		{"#?(:cljs 1 :clj 2)", clj, 2},
+		{"[1 #?(:clj 2) 3]", nil, persistent.NewVector(1, 3)},
+		{"[1 #?@(:gojure [2 3] :clj [4]) 5]", clj, persistent.NewVector(1, 2, 3, 5)},
+		{"(#?@(:gojure ()) 1)", nil, persistent.NewList(1)},
+		{"#?(:clj #js {} :gojure #queue [])", nil, persistent.NewQueue()},
+		{`#?(:clj #"(?=a)b" :gojure 1)`, nil, 1},
+		{`[#?(:clj [#"(a)\1"]) 2]`, nil, persistent.NewVector(2)},
+	}
+	for _, c := range cases {
+		r := FromString(c.source)
+		r.Features = c.features
+		form, err := r.Read()
+		if err != nil || !lang.Equiv(form, c.expected) {
+			t.Errorf("Case '%s' expected to give %v, gave %v %v.", c.source, c.expected, form, err)
+		}
+	}
+
+	for _, s := range []string{"#?@(:gojure [1])", "#?(:gojure)", "#?(gojure 1)", "#?[:gojure 1]", "[#?@(:gojure 1)]", `#?(:gojure #"(?=a)b" :clj 1)`} {
+		if form, err := FromString(s).Read(); err == nil {
+			t.Errorf("Case '%s' should fail, gave %v.", s, form)
+		}
+	}
+
+	r := FromString("#?@(:gojure [1] :clj #js [2])")
+	r.PreserveConditionals = true
+	form, err := r.Read()
+	rc, ok := form.(*ReaderConditional)
+	if err != nil || !ok || !rc.Splicing || len(lang.Items(rc.Form)) != 4 {
+		t.Fatalf("Expected a preserved reader conditional, gave %v %v.", form, err)
+	}
+	tl, ok := rc.Form.Rest().Rest().Rest().First().(*TaggedLiteral)
+	if !ok || tl.Tag != (lang.Symbol{Name: "js"}) {
+		t.Errorf("Expected a preserved tagged literal in %v.", rc)
+	}
+}
@@ -0,0 +1,179 @@
+package reader
+
+// This implementation is practically copied from Clojure's
+// clojure.lang.LispReader.SyntaxQuoteReader.
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tcard/gojure/lang"
+	"github.com/tcard/gojure/persistent"
+)
+
+// CoreNS is the namespace of the core functions that syntax-quote expands into.
+const CoreNS = "gojure.core"
+
+var (
+	unquoteSym         = lang.Symbol{NS: CoreNS, Name: "unquote"}
+	unquoteSplicingSym = lang.Symbol{NS: CoreNS, Name: "unquote-splicing"}
+	quoteSym           = lang.Symbol{Name: "quote"}
+	seqSym             = lang.Symbol{NS: CoreNS, Name: "seq"}
+	concatSym          = lang.Symbol{NS: CoreNS, Name: "concat"}
+	listSym            = lang.Symbol{NS: CoreNS, Name: "list"}
+	applySym           = lang.Symbol{NS: CoreNS, Name: "apply"}
+	vectorSym          = lang.Symbol{NS: CoreNS, Name: "vector"}
+	hashMapSym         = lang.Symbol{NS: CoreNS, Name: "hash-map"}
+	hashSetSym         = lang.Symbol{NS: CoreNS, Name: "hash-set"}
+)
+
+// Special forms are never namespace-qualified by syntax-quote.
+var specialForms = map[string]bool{
+	"def": true, "fn*": true, "if": true, "quote": true, "import": true, "let*": true,
+	"loop*": true, "recur": true, "do": true, "var": true, "&": true,
+}
+
+var gensymID int64
+
+// Reads the form after a syntax-quote (`), and gives the expansion of it: a form
+// that, when evaluated, makes the quoted form with the unquoted (~) forms in it
+// evaluated, and the unquote-spliced (~@) ones spliced into their enclosing
+// collection.
+func (r GojureReader) readSyntaxQuote() (interface{}, error) {
+	form, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	return r.syntaxQuote(form, map[string]lang.Symbol{})
+}
+
+// Reads the form after an unquote (~) or unquote-splicing (~@), giving it wrapped
+// in a call to gojure.core/unquote or gojure.core/unquote-splicing.
+func (r GojureReader) readUnquote() (interface{}, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	sym := unquoteSplicingSym
+	if c != '@' {
+		r.UnreadByte()
+		sym = unquoteSym
+	}
+	form, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	return persistent.NewList(sym, form), nil
+}
+
+// Gives the syntax-quote expansion of form. gensyms holds the symbols already
+// generated for auto-gensyms like foo#.
+func (r GojureReader) syntaxQuote(form interface{}, gensyms map[string]lang.Symbol) (interface{}, error) {
+	switch v := form.(type) {
+	case lang.Symbol:
+		return persistent.NewList(quoteSym, r.qualify(v, gensyms)), nil
+	case *persistent.List:
+		if v == nil {
+			return persistent.NewList(listSym), nil
+		}
+		if isCall(v, unquoteSym) {
+			return v.Rest().First(), nil
+		}
+		if isCall(v, unquoteSplicingSym) {
+			return nil, errors.New("unquote-splicing (~@) not in a collection.")
+		}
+		items := []interface{}{}
+		for ; v != nil; v = v.Rest() {
+			items = append(items, v.First())
+		}
+		return r.syntaxQuoteItems(items, gensyms)
+	case *persistent.Vector:
+		items := []interface{}{}
+		for i := 0; i < v.Count(); i++ {
+			items = append(items, v.Nth(i))
+		}
+		return r.syntaxQuoteApplied(vectorSym, items, gensyms)
+	case *persistent.HashMap:
+		items := []interface{}{}
+		for it := v.Iterator(); it.Next(); {
+			items = append(items, it.Key(), it.Val())
+		}
+		return r.syntaxQuoteApplied(hashMapSym, items, gensyms)
+	case *persistent.HashSet:
+		items := []interface{}{}
+		for it := v.Iterator(); it.Next(); {
+			items = append(items, it.Item())
+		}
+		return r.syntaxQuoteApplied(hashSetSym, items, gensyms)
+	case nil, bool, string, lang.Keyword, lang.Char:
+		return v, nil
+	}
+	if lang.IsNumber(form) {
+		return form, nil
+	}
+	return persistent.NewList(quoteSym, form), nil
+}
+
+// Gives (gojure.core/apply ctor (gojure.core/seq (gojure.core/concat ...))) for the
+// syntax-quoted items of a collection.
+func (r GojureReader) syntaxQuoteApplied(ctor lang.Symbol, items []interface{}, gensyms map[string]lang.Symbol) (interface{}, error) {
+	seq, err := r.syntaxQuoteItems(items, gensyms)
+	if err != nil {
+		return nil, err
+	}
+	return persistent.NewList(applySym, ctor, seq), nil
+}
+
+// Gives (gojure.core/seq (gojure.core/concat ...)) for the syntax-quoted items of
+// a collection. Each item is wrapped in a one-element list, except unquote-spliced
+// ones, which are concatenated as they are.
+func (r GojureReader) syntaxQuoteItems(items []interface{}, gensyms map[string]lang.Symbol) (interface{}, error) {
+	parts := []interface{}{concatSym}
+	for _, x := range items {
+		l, _ := x.(*persistent.List)
+		switch {
+		case isCall(l, unquoteSym):
+			parts = append(parts, persistent.NewList(listSym, l.Rest().First()))
+		case isCall(l, unquoteSplicingSym):
+			parts = append(parts, l.Rest().First())
+		default:
+			q, err := r.syntaxQuote(x, gensyms)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, persistent.NewList(listSym, q))
+		}
+	}
+	return persistent.NewList(seqSym, persistent.NewList(parts...)), nil
+}
+
+// Namespace-qualifies a syntax-quoted symbol. Special forms and symbols that start
+// or end with a dot are kept as they are; auto-gensyms like foo# are replaced by
+// a unique symbol, the same for all of them in the syntax-quoted form; the rest
+// are resolved as by r.Resolve, or else qualified with the current namespace.
+func (r GojureReader) qualify(sym lang.Symbol, gensyms map[string]lang.Symbol) lang.Symbol {
+	if sym.NS != "" || specialForms[sym.Name] || strings.HasPrefix(sym.Name, ".") || strings.HasSuffix(sym.Name, ".") {
+		return sym
+	}
+	if strings.HasSuffix(sym.Name, "#") {
+		gs, ok := gensyms[sym.Name]
+		if !ok {
+			id := atomic.AddInt64(&gensymID, 1)
+			gs = lang.Symbol{Name: strings.TrimSuffix(sym.Name, "#") + "__" + strconv.FormatInt(id, 10) + "__auto__"}
+			gensyms[sym.Name] = gs
+		}
+		return gs
+	}
+	if r.Resolve != nil {
+		return r.Resolve(sym)
+	}
+	return lang.Symbol{NS: r.NS, Name: sym.Name}
+}
+
+// Tells whether l is a call to sym with one argument. Symbols are compared by
+// equivalence, as the one in l may carry metadata.
+func isCall(l *persistent.List, sym lang.Symbol) bool {
+	return l != nil && lang.Equiv(l.First(), sym) && l.Rest() != nil && l.Rest().Rest() == nil
+}